@@ -0,0 +1,113 @@
+package alarms
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"realtime-app/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Следующий уровень эскалации для каждой активной неквитированной тревоги.
+// Политика для конкретного типа датчика важнее общей политики того же уровня.
+const pendingEscalationsQuery = `
+	SELECT a.id AS alarm_id, a.type, p.id AS policy_id, p.name AS policy_name, p.tier,
+	       a.raised_at + make_interval(secs => p.delay_seconds) AS due_at
+	FROM alarms a
+	JOIN LATERAL (
+		SELECT * FROM escalation_policies ep
+		WHERE ep.enabled
+		  AND (ep.type = a.type OR ep.type IS NULL)
+		  AND ep.tier > a.escalation_tier
+		ORDER BY ep.tier, ep.type NULLS LAST
+		LIMIT 1
+	) p ON TRUE
+	WHERE a.state = 'active'
+	  AND a.acknowledged_at IS NULL
+	  AND NOT a.shelved
+	  AND NOT a.suppressed`
+
+// PendingEscalations возвращает запланированные эскалации в порядке наступления
+func PendingEscalations(db *sqlx.DB) ([]models.PendingEscalation, error) {
+	pending := []models.PendingEscalation{}
+	err := db.Select(&pending, pendingEscalationsQuery+" ORDER BY due_at")
+	return pending, err
+}
+
+// Escalate переводит на следующий уровень тревоги, срок эскалации которых
+// наступил, записывает историю оповещения и рассылает событие escalated.
+// Escalate выполняют все экземпляры; тревогу, уже переведенную другим
+// экземпляром, повторно не эскалирует. Ошибка одной тревоги не мешает
+// эскалации остальных; ошибки возвращаются вместе и пишутся в журнал в Run.
+func (m *Manager) Escalate() error {
+	var due []models.PendingEscalation
+	if err := m.db.Select(&due, pendingEscalationsQuery+" AND a.raised_at + make_interval(secs => p.delay_seconds) <= CURRENT_TIMESTAMP"); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, p := range due {
+		alarm, escalated, err := m.escalate(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alarm %d: %w", p.AlarmID, err))
+			continue
+		}
+		if !escalated {
+			continue
+		}
+
+		m.mu.Lock()
+		if current, ok := m.active[activeKey(alarm)]; ok && current.ID == alarm.ID {
+			m.active[activeKey(alarm)] = alarm
+		}
+		handlers := m.handlers
+		m.mu.Unlock()
+
+		m.emit(handlers, models.AlarmEvent{
+			Event: models.EventAlarmEscalated,
+			Alarm: alarm,
+			Tier:  p.Tier,
+			Time:  time.Now(),
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// escalate переводит тревогу на уровень p.Tier. escalated = false, если
+// тревога уже на этом уровне или выше, снята или квитирована
+func (m *Manager) escalate(p models.PendingEscalation) (alarm models.Alarm, escalated bool, err error) {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return alarm, false, err
+	}
+	defer tx.Rollback()
+
+	// Условие на уровень и строчная блокировка UPDATE не дают двум экземплярам
+	// эскалировать тревогу дважды: второй UPDATE дождется первого и не найдет строку
+	err = tx.Get(&alarm, `
+		UPDATE alarms SET escalation_tier = $1
+		WHERE id = $2 AND escalation_tier < $1
+		  AND state = 'active' AND acknowledged_at IS NULL
+		RETURNING *`,
+		p.Tier, p.AlarmID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return alarm, false, nil
+	}
+	if err != nil {
+		return alarm, false, err
+	}
+
+	// Получатели уровня фиксируются на момент эскалации
+	if _, err := tx.Exec(`
+		INSERT INTO alarm_escalations (alarm_id, policy_id, tier, recipients)
+		SELECT $1, $2, $3, COALESCE(string_agg(name || ' <' || target || '>', ', ' ORDER BY id), '')
+		FROM notification_channels
+		WHERE enabled AND tier = $3`,
+		p.AlarmID, p.PolicyID, p.Tier); err != nil {
+		return alarm, false, err
+	}
+
+	return alarm, true, tx.Commit()
+}
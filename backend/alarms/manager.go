@@ -0,0 +1,273 @@
+package alarms
+
+import (
+	"fmt"
+	"log"
+	"realtime-app/models"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// EventHandler вызывается на каждое событие по тревоге
+type EventHandler func(event models.AlarmEvent)
+
+// condition - условия оповещения по датчику: привязка к оборудованию,
+// откладывание оператором и подавление по статусу оборудования
+type condition struct {
+	equipmentID *int
+	shelved     bool
+	suppressed  bool
+}
+
+// ThresholdSource - источник действующих порогов (store.ThresholdStore)
+type ThresholdSource interface {
+	Effective(sensorType string, equipmentID *int) (models.Threshold, bool)
+}
+
+// Manager отслеживает выход показаний за пороги, поднимает и снимает тревоги
+type Manager struct {
+	db         *sqlx.DB
+	thresholds ThresholdSource
+
+	mu         sync.Mutex
+	active     map[string]models.Alarm // активные тревоги по activeKey
+	conditions map[string]condition
+	handlers   []EventHandler
+}
+
+func NewManager(db *sqlx.DB, thresholds ThresholdSource) *Manager {
+	return &Manager{
+		db:         db,
+		thresholds: thresholds,
+		active:     make(map[string]models.Alarm),
+		conditions: make(map[string]condition),
+	}
+}
+
+// OnEvent подписывает обработчик на события тревог
+func (m *Manager) OnEvent(handler EventHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Load восстанавливает активные тревоги из БД после перезапуска
+func (m *Manager) Load() error {
+	var alarms []models.Alarm
+	if err := m.db.Select(&alarms, "SELECT * FROM alarms WHERE state = $1", models.AlarmStateActive); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	for _, a := range alarms {
+		m.active[activeKey(a)] = a
+	}
+	m.mu.Unlock()
+
+	return m.Refresh()
+}
+
+// Run периодически обновляет откладывание и подавление тревог
+// и выполняет наступившие эскалации
+func (m *Manager) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := m.Refresh(); err != nil {
+			log.Printf("Alarm conditions refresh error: %v", err)
+		}
+		if err := m.Escalate(); err != nil {
+			log.Printf("Alarm escalation error: %v", err)
+		}
+	}
+}
+
+// Active возвращает копию списка активных тревог
+func (m *Manager) Active() []models.Alarm {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]models.Alarm, 0, len(m.active))
+	for _, a := range m.active {
+		result = append(result, a)
+	}
+	return result
+}
+
+// Evaluate сравнивает показание с действующим для его оборудования порогом:
+// поднимает тревогу при выходе за границы и снимает её при возврате в диапазон
+func (m *Manager) Evaluate(reading models.SensorData) error {
+	threshold, ok := m.thresholds.Effective(reading.Type, reading.EquipmentID)
+	if !ok {
+		return nil
+	}
+	direction, limit := violation(reading.Value, threshold)
+
+	m.mu.Lock()
+	current, isActive := m.active[reading.Type]
+	cond := m.conditions[reading.Type]
+
+	var event string
+	var alarm models.Alarm
+	var err error
+	switch {
+	case !isActive && direction != "":
+		event = models.EventAlarmRaised
+		err = m.db.Get(&alarm, `
+			INSERT INTO alarms (type, state, direction, value, limit_value, equipment_id, shelved, suppressed)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING *`,
+			reading.Type, models.AlarmStateActive, direction, reading.Value, limit,
+			cond.equipmentID, cond.shelved, cond.suppressed)
+		if err == nil {
+			m.active[reading.Type] = alarm
+		}
+	case isActive && direction == "":
+		event = models.EventAlarmCleared
+		err = m.db.Get(&alarm, `
+			UPDATE alarms SET state = $1, cleared_at = CURRENT_TIMESTAMP
+			WHERE id = $2
+			RETURNING *`,
+			models.AlarmStateCleared, current.ID)
+		if err == nil {
+			delete(m.active, reading.Type)
+		}
+	}
+	handlers := m.handlers
+	m.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("alarm %s error: %v", event, err)
+	}
+	if event != "" {
+		m.emit(handlers, models.AlarmEvent{Event: event, Alarm: alarm, Time: time.Now()})
+	}
+	return nil
+}
+
+// Refresh перечитывает из БД отложенные датчики и статус оборудования и
+// переводит активные тревоги в соответствующее состояние
+func (m *Manager) Refresh() error {
+	var links []struct {
+		Type        string `db:"type"`
+		EquipmentID int    `db:"id_equipment"`
+		Status      string `db:"status"`
+	}
+	if err := m.db.Select(&links, `
+		SELECT se.type, se.id_equipment, COALESCE(e.status, '') AS status
+		FROM sensor_equipment se
+		JOIN equipment e ON e.id = se.id_equipment`); err != nil {
+		return err
+	}
+
+	var shelvedTypes []string
+	if err := m.db.Select(&shelvedTypes, `
+		SELECT DISTINCT type FROM alarm_shelves
+		WHERE unshelved_at IS NULL AND expires_at > CURRENT_TIMESTAMP`); err != nil {
+		return err
+	}
+
+	conditions := make(map[string]condition)
+	for _, l := range links {
+		equipmentID := l.EquipmentID
+		conditions[l.Type] = condition{
+			equipmentID: &equipmentID,
+			suppressed:  l.Status == models.EquipmentUnderRepair,
+		}
+	}
+	for _, t := range shelvedTypes {
+		c := conditions[t]
+		c.shelved = true
+		conditions[t] = c
+	}
+
+	m.mu.Lock()
+	m.conditions = conditions
+
+	var events []models.AlarmEvent
+	var firstErr error
+	for key, current := range m.active {
+		cond := conditions[current.Type]
+		if current.Shelved == cond.shelved && current.Suppressed == cond.suppressed {
+			continue
+		}
+
+		var alarm models.Alarm
+		if err := m.db.Get(&alarm, `
+			UPDATE alarms SET shelved = $1, suppressed = $2
+			WHERE id = $3
+			RETURNING *`,
+			cond.shelved, cond.suppressed, current.ID); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		m.active[key] = alarm
+
+		event := models.EventAlarmReturned
+		switch {
+		case alarm.Shelved && !current.Shelved:
+			event = models.EventAlarmShelved
+		case alarm.Suppressed && !current.Suppressed:
+			event = models.EventAlarmSuppressed
+		case !alarm.Annunciated():
+			continue
+		}
+		events = append(events, models.AlarmEvent{Event: event, Alarm: alarm, Time: time.Now()})
+	}
+	handlers := m.handlers
+	m.mu.Unlock()
+
+	for _, e := range events {
+		m.emit(handlers, e)
+	}
+	return firstErr
+}
+
+// Annotate публикует квитирование или комментарий оператора, записанный в БД,
+// и обновляет состояние активной тревоги
+func (m *Manager) Annotate(event string, alarm models.Alarm, comment models.AlarmComment) {
+	m.mu.Lock()
+	if current, ok := m.active[activeKey(alarm)]; ok && current.ID == alarm.ID {
+		m.active[activeKey(alarm)] = alarm
+	}
+	handlers := m.handlers
+	m.mu.Unlock()
+
+	m.emit(handlers, models.AlarmEvent{Event: event, Alarm: alarm, Comment: &comment, Time: time.Now()})
+}
+
+// activeKey - ключ активной тревоги: тип датчика, для тревоги по прогнозу
+// с префиксом, чтобы она не заменяла тревогу по показанию того же датчика
+func activeKey(a models.Alarm) string {
+	if a.Kind == models.AlarmKindPredicted {
+		return predictedKey(a.Type)
+	}
+	return a.Type
+}
+
+func predictedKey(sensorType string) string {
+	return models.AlarmKindPredicted + "/" + sensorType
+}
+
+func (m *Manager) emit(handlers []EventHandler, event models.AlarmEvent) {
+	for _, h := range handlers {
+		h(event)
+	}
+}
+
+// violation определяет направление нарушения и нарушенную границу
+func violation(value float64, t models.Threshold) (string, float64) {
+	switch {
+	case value > t.MaxValue:
+		return models.AlarmHigh, t.MaxValue
+	case value < t.MinValue:
+		return models.AlarmLow, t.MinValue
+	default:
+		return "", 0
+	}
+}
@@ -0,0 +1,214 @@
+package alarms_test
+
+import (
+	"errors"
+	"realtime-app/alarms"
+	"realtime-app/models"
+	"realtime-app/store"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var alarmColumns = []string{
+	"id", "type", "state", "direction", "value", "limit_value", "raised_at",
+	"cleared_at", "acknowledged_at", "acknowledged_by", "equipment_id", "shelved", "suppressed", "escalation_tier",
+}
+
+func newMockManager(t *testing.T) (*alarms.Manager, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	thresholds := store.NewThresholdStore([]models.Threshold{
+		{Type: "temperature", MinValue: 20, MaxValue: 35},
+		{Type: "pressure", MinValue: 900, MaxValue: 1100},
+	})
+	return alarms.NewManager(sqlx.NewDb(db, "sqlmock"), thresholds), mock, func() { db.Close() }
+}
+
+func TestEvaluateRaisesAndClears(t *testing.T) {
+	manager, mock, closeDB := newMockManager(t)
+	defer closeDB()
+
+	var events []models.AlarmEvent
+	manager.OnEvent(func(e models.AlarmEvent) { events = append(events, e) })
+
+	now := time.Now()
+
+	mock.ExpectQuery("INSERT INTO alarms").
+		WithArgs("temperature", models.AlarmStateActive, models.AlarmHigh, 40.0, 35.0, nil, false, false).
+		WillReturnRows(sqlmock.NewRows(alarmColumns).
+			AddRow(1, "temperature", "active", "high", 40.0, 35.0, now, nil, nil, nil, nil, false, false, 0))
+	mock.ExpectQuery("UPDATE alarms SET state").
+		WithArgs(models.AlarmStateCleared, 1).
+		WillReturnRows(sqlmock.NewRows(alarmColumns).
+			AddRow(1, "temperature", "cleared", "high", 40.0, 35.0, now, now, nil, nil, nil, false, false, 0))
+
+	// Повторное нарушение не поднимает вторую тревогу
+	assert.NoError(t, manager.Evaluate(models.SensorData{Type: "temperature", Value: 40}))
+	assert.NoError(t, manager.Evaluate(models.SensorData{Type: "temperature", Value: 41}))
+	assert.Len(t, manager.Active(), 1)
+
+	assert.NoError(t, manager.Evaluate(models.SensorData{Type: "temperature", Value: 30}))
+	assert.Empty(t, manager.Active())
+
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.EventAlarmRaised, events[0].Event)
+		assert.Equal(t, models.EventAlarmCleared, events[1].Event)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEvaluateSuppressesEquipmentUnderRepair(t *testing.T) {
+	manager, mock, closeDB := newMockManager(t)
+	defer closeDB()
+
+	var events []models.AlarmEvent
+	manager.OnEvent(func(e models.AlarmEvent) { events = append(events, e) })
+
+	mock.ExpectQuery("SELECT se.type, se.id_equipment").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id_equipment", "status"}).
+			AddRow("pressure", 3, models.EquipmentUnderRepair))
+	mock.ExpectQuery("SELECT DISTINCT type FROM alarm_shelves").
+		WillReturnRows(sqlmock.NewRows([]string{"type"}))
+	mock.ExpectQuery("INSERT INTO alarms").
+		WithArgs("pressure", models.AlarmStateActive, models.AlarmLow, 850.0, 900.0, 3, false, true).
+		WillReturnRows(sqlmock.NewRows(alarmColumns).
+			AddRow(2, "pressure", "active", "low", 850.0, 900.0, time.Now(), nil, nil, nil, 3, false, true, 0))
+
+	assert.NoError(t, manager.Refresh())
+
+	assert.NoError(t, manager.Evaluate(models.SensorData{Type: "pressure", Value: 850}))
+
+	if assert.Len(t, events, 1) {
+		assert.True(t, events[0].Alarm.Suppressed)
+		assert.False(t, events[0].Alarm.Annunciated())
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshReturnsUnshelvedAlarm(t *testing.T) {
+	manager, mock, closeDB := newMockManager(t)
+	defer closeDB()
+
+	var events []models.AlarmEvent
+	manager.OnEvent(func(e models.AlarmEvent) { events = append(events, e) })
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT \\* FROM alarms WHERE state").
+		WillReturnRows(sqlmock.NewRows(alarmColumns).
+			AddRow(5, "humidity", "active", "high", 90.0, 80.0, now, nil, nil, nil, nil, true, false, 0))
+	// Откладывание истекло
+	mock.ExpectQuery("SELECT se.type, se.id_equipment").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id_equipment", "status"}))
+	mock.ExpectQuery("SELECT DISTINCT type FROM alarm_shelves").
+		WillReturnRows(sqlmock.NewRows([]string{"type"}))
+	mock.ExpectQuery("UPDATE alarms SET shelved").
+		WithArgs(false, false, 5).
+		WillReturnRows(sqlmock.NewRows(alarmColumns).
+			AddRow(5, "humidity", "active", "high", 90.0, 80.0, now, nil, nil, nil, nil, false, false, 0))
+
+	assert.NoError(t, manager.Load())
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.EventAlarmReturned, events[0].Event)
+		assert.True(t, events[0].Alarm.Annunciated())
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEscalateNotifiesNextTier(t *testing.T) {
+	manager, mock, closeDB := newMockManager(t)
+	defer closeDB()
+
+	var events []models.AlarmEvent
+	manager.OnEvent(func(e models.AlarmEvent) { events = append(events, e) })
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT a.id AS alarm_id").
+		WillReturnRows(sqlmock.NewRows([]string{"alarm_id", "type", "policy_id", "policy_name", "tier", "due_at"}).
+			AddRow(9, "temperature", 1, "Начальник смены", 1, now))
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE alarms SET escalation_tier").
+		WithArgs(1, 9).
+		WillReturnRows(sqlmock.NewRows(alarmColumns).
+			AddRow(9, "temperature", "active", "high", 40.0, 35.0, now, nil, nil, nil, nil, false, false, 1))
+	mock.ExpectExec("INSERT INTO alarm_escalations").
+		WithArgs(9, 1, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, manager.Escalate())
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.EventAlarmEscalated, events[0].Event)
+		assert.Equal(t, 1, events[0].Tier)
+		assert.Equal(t, 1, events[0].Alarm.EscalationTier)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEscalateSkipsAlarmEscalatedElsewhere(t *testing.T) {
+	manager, mock, closeDB := newMockManager(t)
+	defer closeDB()
+
+	var events []models.AlarmEvent
+	manager.OnEvent(func(e models.AlarmEvent) { events = append(events, e) })
+
+	// Другой экземпляр уже перевел тревогу на уровень 1: UPDATE не находит строку,
+	// история и оповещение не пишутся
+	mock.ExpectQuery("SELECT a.id AS alarm_id").
+		WillReturnRows(sqlmock.NewRows([]string{"alarm_id", "type", "policy_id", "policy_name", "tier", "due_at"}).
+			AddRow(9, "temperature", 1, "Начальник смены", 1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE alarms SET escalation_tier .* AND escalation_tier < \\$1").
+		WithArgs(1, 9).
+		WillReturnRows(sqlmock.NewRows(alarmColumns))
+	mock.ExpectRollback()
+
+	assert.NoError(t, manager.Escalate())
+
+	assert.Empty(t, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEscalateContinuesAfterFailure(t *testing.T) {
+	manager, mock, closeDB := newMockManager(t)
+	defer closeDB()
+
+	var events []models.AlarmEvent
+	manager.OnEvent(func(e models.AlarmEvent) { events = append(events, e) })
+
+	// Ошибка эскалации тревоги 8 не мешает эскалации тревоги 9
+	now := time.Now()
+	mock.ExpectQuery("SELECT a.id AS alarm_id").
+		WillReturnRows(sqlmock.NewRows([]string{"alarm_id", "type", "policy_id", "policy_name", "tier", "due_at"}).
+			AddRow(8, "pressure", 1, "Начальник смены", 1, now).
+			AddRow(9, "temperature", 1, "Начальник смены", 1, now))
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE alarms SET escalation_tier").
+		WithArgs(1, 8).
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE alarms SET escalation_tier").
+		WithArgs(1, 9).
+		WillReturnRows(sqlmock.NewRows(alarmColumns).
+			AddRow(9, "temperature", "active", "high", 40.0, 35.0, now, nil, nil, nil, nil, false, false, 1))
+	mock.ExpectExec("INSERT INTO alarm_escalations").
+		WithArgs(9, 1, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := manager.Escalate()
+
+	assert.ErrorContains(t, err, "alarm 8")
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, 9, events[0].Alarm.ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
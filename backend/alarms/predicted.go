@@ -0,0 +1,78 @@
+package alarms
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"realtime-app/models"
+	"time"
+)
+
+// RaisePredicted поднимает тревогу о прогнозируемом выходе за границу.
+// Тревога по прогнозу одна на датчик: если ее уже поднял другой экземпляр,
+// она только запоминается, а повторного события нет.
+func (m *Manager) RaisePredicted(c models.PredictedCrossing) error {
+	key := predictedKey(c.Type)
+
+	m.mu.Lock()
+	if _, ok := m.active[key]; ok {
+		m.mu.Unlock()
+		return nil
+	}
+	cond := m.conditions[c.Type]
+
+	var alarm models.Alarm
+	raised := true
+	err := m.db.Get(&alarm, `
+		INSERT INTO alarms (type, kind, state, direction, value, limit_value, predicted_at, equipment_id, shelved, suppressed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (type) WHERE kind = 'predicted' AND state = 'active' DO NOTHING
+		RETURNING *`,
+		c.Type, models.AlarmKindPredicted, models.AlarmStateActive, c.Direction, c.PredictedValue, c.LimitValue,
+		c.At, c.EquipmentID, cond.shelved, cond.suppressed)
+	if errors.Is(err, sql.ErrNoRows) {
+		raised = false
+		err = m.db.Get(&alarm, "SELECT * FROM alarms WHERE type = $1 AND kind = $2 AND state = $3",
+			c.Type, models.AlarmKindPredicted, models.AlarmStateActive)
+	}
+	if err == nil {
+		m.active[key] = alarm
+	}
+	handlers := m.handlers
+	m.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("predicted alarm raised error: %v", err)
+	}
+	if raised {
+		m.emit(handlers, models.AlarmEvent{Event: models.EventAlarmRaised, Alarm: alarm, Time: time.Now()})
+	}
+	return nil
+}
+
+// ClearPredicted снимает тревогу по прогнозу, когда прогноз вернулся в
+// пределы границ. Тревогу мог поднять другой экземпляр, поэтому она
+// снимается в БД, даже если этому экземпляру не известна.
+func (m *Manager) ClearPredicted(sensorType string) error {
+	m.mu.Lock()
+	var alarm models.Alarm
+	err := m.db.Get(&alarm, `
+		UPDATE alarms SET state = $1, cleared_at = CURRENT_TIMESTAMP
+		WHERE type = $2 AND kind = $3 AND state = $4
+		RETURNING *`,
+		models.AlarmStateCleared, sensorType, models.AlarmKindPredicted, models.AlarmStateActive)
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		delete(m.active, predictedKey(sensorType))
+	}
+	handlers := m.handlers
+	m.mu.Unlock()
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("predicted alarm cleared error: %v", err)
+	}
+	m.emit(handlers, models.AlarmEvent{Event: models.EventAlarmCleared, Alarm: alarm, Time: time.Now()})
+	return nil
+}
@@ -0,0 +1,78 @@
+package alarms_test
+
+import (
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var predictedColumns = append(append([]string{}, alarmColumns...), "kind", "predicted_at")
+
+func TestPredictedAlarmRaisedOnceAndCleared(t *testing.T) {
+	manager, mock, closeDB := newMockManager(t)
+	defer closeDB()
+
+	var events []models.AlarmEvent
+	manager.OnEvent(func(e models.AlarmEvent) { events = append(events, e) })
+
+	now := time.Now()
+	at := now.Add(20 * time.Minute)
+	crossing := models.PredictedCrossing{Type: "temperature", Direction: models.AlarmHigh, LimitValue: 35, PredictedValue: 35.2, At: at}
+	mock.ExpectQuery("INSERT INTO alarms .* ON CONFLICT").
+		WithArgs("temperature", models.AlarmKindPredicted, models.AlarmStateActive, models.AlarmHigh, 35.2, 35.0, at, nil, false, false).
+		WillReturnRows(sqlmock.NewRows(predictedColumns).
+			AddRow(4, "temperature", "active", "high", 35.2, 35.0, now, nil, nil, nil, nil, false, false, 0, "predicted", at))
+
+	assert.NoError(t, manager.RaisePredicted(crossing))
+	// Повторный прогноз с выходом за границу тревогу не дублирует
+	assert.NoError(t, manager.RaisePredicted(crossing))
+
+	mock.ExpectQuery("UPDATE alarms SET state").
+		WithArgs(models.AlarmStateCleared, "temperature", models.AlarmKindPredicted, models.AlarmStateActive).
+		WillReturnRows(sqlmock.NewRows(predictedColumns).
+			AddRow(4, "temperature", "cleared", "high", 35.2, 35.0, now, now, nil, nil, nil, false, false, 0, "predicted", at))
+	assert.NoError(t, manager.ClearPredicted("temperature"))
+
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.EventAlarmRaised, events[0].Event)
+		assert.Equal(t, models.AlarmKindPredicted, events[0].Alarm.Kind)
+		assert.Equal(t, at, *events[0].Alarm.PredictedAt)
+		assert.Equal(t, models.EventAlarmCleared, events[1].Event)
+	}
+	assert.Empty(t, manager.Active())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPredictedAlarmRaisedByAnotherInstance(t *testing.T) {
+	manager, mock, closeDB := newMockManager(t)
+	defer closeDB()
+
+	var events []models.AlarmEvent
+	manager.OnEvent(func(e models.AlarmEvent) { events = append(events, e) })
+
+	now := time.Now()
+	at := now.Add(20 * time.Minute)
+	mock.ExpectQuery("INSERT INTO alarms .* ON CONFLICT").
+		WillReturnRows(sqlmock.NewRows(predictedColumns))
+	mock.ExpectQuery("SELECT \\* FROM alarms WHERE type = \\$1 AND kind = \\$2").
+		WithArgs("temperature", models.AlarmKindPredicted, models.AlarmStateActive).
+		WillReturnRows(sqlmock.NewRows(predictedColumns).
+			AddRow(4, "temperature", "active", "high", 35.2, 35.0, now, nil, nil, nil, nil, false, false, 0, "predicted", at))
+
+	assert.NoError(t, manager.RaisePredicted(models.PredictedCrossing{Type: "temperature", Direction: models.AlarmHigh, LimitValue: 35, PredictedValue: 35.2, At: at}))
+
+	// Тревога запомнена, но событие уже разослал другой экземпляр
+	assert.Empty(t, events)
+	assert.Len(t, manager.Active(), 1)
+
+	// Прогноз в пределах, тревоги по датчику нет: снимать нечего
+	mock.ExpectQuery("UPDATE alarms SET state").
+		WithArgs(models.AlarmStateCleared, "pressure", models.AlarmKindPredicted, models.AlarmStateActive).
+		WillReturnRows(sqlmock.NewRows(predictedColumns))
+	assert.NoError(t, manager.ClearPredicted("pressure"))
+	assert.Empty(t, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
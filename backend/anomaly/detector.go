@@ -0,0 +1,146 @@
+package anomaly
+
+import (
+	"math"
+	"realtime-app/models"
+	"time"
+)
+
+// Параметры детектора по умолчанию
+const (
+	DefaultAlpha         = 0.05 // вес нового показания в скользящем среднем
+	DefaultSeasonalAlpha = 0.01 // вес показания в уровне часа суток
+	DefaultThreshold     = 4.0  // z-оценка, начиная с которой показание аномально
+	DefaultWarmup        = 30   // показаний до начала оценки
+)
+
+// baseline - экспоненциально взвешенные среднее и дисперсия
+type baseline struct {
+	mean     float64
+	variance float64
+	n        int
+}
+
+// score возвращает z-оценку значения; false - базы для оценки еще нет
+func (b *baseline) score(value float64, warmup int) (float64, bool) {
+	if b.n < warmup || b.variance <= 0 {
+		return 0, false
+	}
+	return (value - b.mean) / math.Sqrt(b.variance), true
+}
+
+// update учитывает значение. Пока показаний меньше 1/alpha, вес
+// увеличен до 1/(n+1), чтобы первое значение не доминировало в среднем.
+func (b *baseline) update(value, alpha float64) {
+	if b.n == 0 {
+		b.mean, b.variance, b.n = value, 0, 1
+		return
+	}
+	a := math.Max(alpha, 1/float64(b.n+1))
+	diff := value - b.mean
+	b.mean += a * diff
+	b.variance = (1 - a) * (b.variance + a*diff*diff)
+	b.n++
+}
+
+// series - состояние датчика: скользящая база и база по часам суток
+type series struct {
+	ewma   baseline
+	hourly [24]baseline
+}
+
+type seriesKey struct {
+	sensorType  string
+	equipmentID int
+}
+
+// Detector оценивает показания потока по мере поступления. Каждое показание
+// сравнивается с базами, построенными по предыдущим, затем учитывается в них.
+// Детектор не потокобезопасен.
+type Detector struct {
+	Alpha         float64
+	SeasonalAlpha float64
+	Threshold     float64
+	Warmup        int
+
+	series map[seriesKey]*series
+}
+
+func NewDetector() *Detector {
+	return &Detector{
+		Alpha:         DefaultAlpha,
+		SeasonalAlpha: DefaultSeasonalAlpha,
+		Threshold:     DefaultThreshold,
+		Warmup:        DefaultWarmup,
+		series:        make(map[seriesKey]*series),
+	}
+}
+
+// Observe учитывает показание и возвращает аномалию, если отклонение от
+// скользящей или сезонной базы достигает порога; иначе nil
+func (d *Detector) Observe(reading models.SensorData) *models.Anomaly {
+	at := reading.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	s := d.get(reading.Type, reading.EquipmentID)
+	hour := &s.hourly[at.UTC().Hour()]
+
+	var found *models.Anomaly
+	for _, c := range []struct {
+		method string
+		base   *baseline
+	}{
+		{models.AnomalyEWMA, &s.ewma},
+		{models.AnomalySeasonal, hour},
+	} {
+		z, ok := c.base.score(reading.Value, d.Warmup)
+		if !ok || math.Abs(z) < d.Threshold {
+			continue
+		}
+		if found == nil || math.Abs(z) > math.Abs(found.Score) {
+			found = &models.Anomaly{
+				Type:        reading.Type,
+				EquipmentID: reading.EquipmentID,
+				Value:       reading.Value,
+				Expected:    c.base.mean,
+				StdDev:      math.Sqrt(c.base.variance),
+				Score:       z,
+				Method:      c.method,
+				DetectedAt:  at,
+			}
+		}
+	}
+	if found != nil && reading.ID != 0 {
+		id := reading.ID
+		found.SensorDataID = &id
+	}
+
+	s.ewma.update(reading.Value, d.Alpha)
+	hour.update(reading.Value, d.SeasonalAlpha)
+	return found
+}
+
+// Seed задает базу датчика по истории: hour - час суток для сезонной базы,
+// -1 - скользящая база
+func (d *Detector) Seed(sensorType string, equipmentID *int, hour int, mean, variance float64, n int) {
+	s := d.get(sensorType, equipmentID)
+	b := &s.ewma
+	if hour >= 0 && hour < 24 {
+		b = &s.hourly[hour]
+	}
+	*b = baseline{mean: mean, variance: variance, n: n}
+}
+
+func (d *Detector) get(sensorType string, equipmentID *int) *series {
+	key := seriesKey{sensorType: sensorType}
+	if equipmentID != nil {
+		key.equipmentID = *equipmentID
+	}
+	s, ok := d.series[key]
+	if !ok {
+		s = &series{}
+		d.series[key] = s
+	}
+	return s
+}
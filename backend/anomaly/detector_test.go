@@ -0,0 +1,93 @@
+package anomaly_test
+
+import (
+	"realtime-app/anomaly"
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// feed подает ровный сигнал с небольшим колебанием вокруг level
+func feed(d *anomaly.Detector, n int, level float64) {
+	for i := 0; i < n; i++ {
+		value := level + float64(i%5-2)*0.1
+		if a := d.Observe(models.SensorData{Type: "temperature", Value: value, Timestamp: start.Add(time.Duration(i) * time.Second)}); a != nil {
+			panic("unexpected anomaly in steady signal")
+		}
+	}
+}
+
+func TestDetectorFlagsSpikeInsideLimits(t *testing.T) {
+	d := anomaly.NewDetector()
+
+	// До накопления базы показания не оцениваются
+	assert.Nil(t, d.Observe(models.SensorData{Type: "temperature", Value: 25, Timestamp: start}))
+	assert.Nil(t, d.Observe(models.SensorData{Type: "temperature", Value: 60, Timestamp: start}))
+
+	d = anomaly.NewDetector()
+	feed(d, 200, 25)
+
+	// 27 градусов далеко в пределах порогов, но необычно для ровного сигнала
+	a := d.Observe(models.SensorData{ID: 42, Type: "temperature", Value: 27, Timestamp: start.Add(time.Hour)})
+	if assert.NotNil(t, a) {
+		assert.Equal(t, models.AnomalyEWMA, a.Method)
+		assert.Greater(t, a.Score, anomaly.DefaultThreshold)
+		assert.InDelta(t, 25.0, a.Expected, 0.1)
+		assert.Equal(t, 42, *a.SensorDataID)
+	}
+
+	// Другой датчик со своей базой не затронут
+	assert.Nil(t, d.Observe(models.SensorData{Type: "pressure", Value: 1000, Timestamp: start}))
+}
+
+func TestDetectorSeasonalBaseline(t *testing.T) {
+	d := anomaly.NewDetector()
+	feed(d, 200, 25)
+
+	// Ночью этот датчик обычно показывает 18 градусов
+	night := time.Date(2024, 3, 2, 3, 15, 0, 0, time.UTC)
+	d.Seed("temperature", nil, 3, 18, 0.04, 1000)
+	d.Seed("temperature", nil, -1, 25.2, 4, 1000)
+
+	a := d.Observe(models.SensorData{Type: "temperature", Value: 25, Timestamp: night})
+	if assert.NotNil(t, a) {
+		assert.Equal(t, models.AnomalySeasonal, a.Method)
+		assert.InDelta(t, 35.0, a.Score, 0.01)
+	}
+}
+
+func TestMonitorStoresAndEmitsAnomaly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	d := anomaly.NewDetector()
+	d.Seed("humidity", nil, -1, 50, 1, 1000)
+	m := anomaly.NewMonitor(sqlx.NewDb(db, "sqlmock"), d)
+
+	var emitted []models.Anomaly
+	m.OnAnomaly(func(a models.Anomaly) { emitted = append(emitted, a) })
+
+	at := start.Add(time.Minute)
+	mock.ExpectQuery("INSERT INTO anomalies").
+		WithArgs("humidity", nil, 7, 56.0, 50.0, 1.0, 6.0, models.AnomalyEWMA, at).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "equipment_id", "sensor_data_id", "value", "expected", "stddev", "score", "method", "detected_at"}).
+			AddRow(1, "humidity", nil, 7, 56.0, 50.0, 1.0, 6.0, models.AnomalyEWMA, at))
+
+	assert.NoError(t, m.Process(models.SensorData{ID: 7, Type: "humidity", Value: 56, Timestamp: at}))
+	// Обычное показание не сохраняется
+	assert.NoError(t, m.Process(models.SensorData{ID: 8, Type: "humidity", Value: 50.5, Timestamp: at}))
+
+	if assert.Len(t, emitted, 1) {
+		assert.Equal(t, 1, emitted[0].ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
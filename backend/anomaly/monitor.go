@@ -0,0 +1,98 @@
+package anomaly
+
+import (
+	"realtime-app/models"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Handler вызывается на каждую обнаруженную аномалию
+type Handler func(models.Anomaly)
+
+// Monitor прогоняет живой поток показаний через детектор, сохраняет
+// аномалии в таблицу anomalies и рассылает их подписчикам
+type Monitor struct {
+	db *sqlx.DB
+
+	mu       sync.Mutex
+	detector *Detector
+	handlers []Handler
+}
+
+func NewMonitor(db *sqlx.DB, detector *Detector) *Monitor {
+	return &Monitor{db: db, detector: detector}
+}
+
+// OnAnomaly подписывает обработчик на аномалии
+func (m *Monitor) OnAnomaly(handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Warm строит базы детектора по показаниям за window, чтобы после
+// перезапуска оценка начиналась сразу, а не через сутки накопления
+func (m *Monitor) Warm(window time.Duration) error {
+	var rows []struct {
+		Type        string   `db:"type"`
+		EquipmentID *int     `db:"id_equipment"`
+		Hour        int      `db:"hour"`
+		Mean        float64  `db:"mean"`
+		Variance    *float64 `db:"variance"`
+		Samples     int      `db:"samples"`
+	}
+	// hour = -1 - скользящая база по последнему часу окна
+	if err := m.db.Select(&rows, `
+		SELECT type, id_equipment, EXTRACT(HOUR FROM timestamp)::int AS hour,
+		       AVG(value) AS mean, VAR_SAMP(value) AS variance, COUNT(*) AS samples
+		FROM sensor_data
+		WHERE timestamp >= LOCALTIMESTAMP - $1 * INTERVAL '1 second'
+		GROUP BY 1, 2, 3
+		UNION ALL
+		SELECT type, id_equipment, -1,
+		       AVG(value), VAR_SAMP(value), COUNT(*)
+		FROM sensor_data
+		WHERE timestamp >= LOCALTIMESTAMP - INTERVAL '1 hour'
+		GROUP BY 1, 2`,
+		window.Seconds()); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range rows {
+		if r.Variance == nil {
+			continue
+		}
+		m.detector.Seed(r.Type, r.EquipmentID, r.Hour, r.Mean, *r.Variance, r.Samples)
+	}
+	return nil
+}
+
+// Process оценивает показание и сохраняет аномалию, если она найдена
+func (m *Monitor) Process(reading models.SensorData) error {
+	m.mu.Lock()
+	found := m.detector.Observe(reading)
+	handlers := m.handlers
+	m.mu.Unlock()
+	if found == nil {
+		return nil
+	}
+
+	var saved models.Anomaly
+	if err := m.db.Get(&saved, `
+		INSERT INTO anomalies (type, equipment_id, sensor_data_id, value, expected, stddev, score, method, detected_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING *`,
+		found.Type, found.EquipmentID, found.SensorDataID, found.Value, found.Expected,
+		found.StdDev, found.Score, found.Method, found.DetectedAt); err != nil {
+		return err
+	}
+
+	for _, h := range handlers {
+		h(saved)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/validation"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AlarmCommentCallback вызывается после квитирования или добавления комментария
+type AlarmCommentCallback func(event string, alarm models.Alarm, comment models.AlarmComment)
+
+// AcknowledgeAlarm квитирует тревогу от имени оператора с необязательным комментарием
+func AcknowledgeAlarm(db *sqlx.DB, callback AlarmCommentCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		var req struct {
+			AlarmID int    `json:"alarm_id"`
+			User    string `json:"user"`
+			Comment string `json:"comment"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		req.User = strings.TrimSpace(req.User)
+		if err := validation.First(
+			validation.Positive("alarm_id", req.AlarmID),
+			validation.NotEmpty("user", req.User),
+		); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var alarm models.Alarm
+		err = tx.Get(&alarm, `
+			UPDATE alarms SET acknowledged_at = CURRENT_TIMESTAMP, acknowledged_by = $2
+			WHERE id = $1 AND acknowledged_at IS NULL
+			RETURNING *`,
+			req.AlarmID, req.User)
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusConflict, codeConflict, "alarm_id", "Alarm not found or already acknowledged")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to acknowledge alarm")
+			return
+		}
+
+		var comment models.AlarmComment
+		if err := tx.Get(&comment, `
+			INSERT INTO alarm_comments (alarm_id, kind, author, body)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *`,
+			alarm.ID, models.CommentKindAck, req.User, strings.TrimSpace(req.Comment)); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to save acknowledgement")
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			writeInternalError(w, err)
+			return
+		}
+
+		callback(models.EventAlarmAcknowledged, alarm, comment)
+		jsonResponse(w, alarm)
+	}
+}
+
+// AlarmComments: GET ?alarm_id= - журнал квитирования и комментариев по тревоге,
+// POST - добавление комментария
+func AlarmComments(db *sqlx.DB, callback AlarmCommentCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, POST, OPTIONS")
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			alarmID := r.URL.Query().Get("alarm_id")
+			if alarmID == "" {
+				http.Error(w, "alarm_id is required", http.StatusBadRequest)
+				return
+			}
+			comments := []models.AlarmComment{}
+			if err := db.Select(&comments, "SELECT * FROM alarm_comments WHERE alarm_id = $1 ORDER BY created_at, id", alarmID); err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			jsonResponse(w, comments)
+		case http.MethodPost:
+			var req struct {
+				AlarmID int    `json:"alarm_id"`
+				User    string `json:"user"`
+				Comment string `json:"comment"`
+			}
+			if err := decodeBody(r, &req); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			req.User, req.Comment = strings.TrimSpace(req.User), strings.TrimSpace(req.Comment)
+			if err := validation.First(
+				validation.Positive("alarm_id", req.AlarmID),
+				validation.NotEmpty("user", req.User),
+				validation.NotEmpty("comment", req.Comment),
+			); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+
+			var alarm models.Alarm
+			err := db.Get(&alarm, "SELECT * FROM alarms WHERE id = $1", req.AlarmID)
+			if err == sql.ErrNoRows {
+				writeError(w, http.StatusNotFound, codeNotFound, "alarm_id", "Alarm not found")
+				return
+			}
+			if err != nil {
+				writeInternalError(w, err)
+				return
+			}
+
+			var comment models.AlarmComment
+			if err := db.Get(&comment, `
+				INSERT INTO alarm_comments (alarm_id, kind, author, body)
+				VALUES ($1, $2, $3, $4)
+				RETURNING *`,
+				alarm.ID, models.CommentKindComment, req.User, req.Comment); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to save comment")
+				return
+			}
+
+			callback(models.EventAlarmCommented, alarm, comment)
+			jsonResponse(w, comment)
+		default:
+			writeMethodNotAllowed(w)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcknowledgeAlarm(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE alarms SET acknowledged_at").
+		WithArgs(4, "Иванов").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "state", "acknowledged_at", "acknowledged_by"}).
+			AddRow(4, "temperature", "active", now, "Иванов"))
+	mock.ExpectQuery("INSERT INTO alarm_comments").
+		WithArgs(4, models.CommentKindAck, "Иванов", "Проверил датчик").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "alarm_id", "kind", "author", "body", "created_at"}).
+			AddRow(1, 4, "ack", "Иванов", "Проверил датчик", now))
+	mock.ExpectCommit()
+
+	var gotEvent string
+	var gotComment models.AlarmComment
+	callback := func(event string, alarm models.Alarm, comment models.AlarmComment) {
+		gotEvent = event
+		gotComment = comment
+		assert.Equal(t, 4, alarm.ID)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"alarm_id": 4, "user": "Иванов", "comment": "Проверил датчик"})
+	req := httptest.NewRequest("POST", "/api/alarms/ack", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.AcknowledgeAlarm(sqlxDB, callback)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var alarm models.Alarm
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &alarm))
+	if assert.NotNil(t, alarm.AcknowledgedBy) {
+		assert.Equal(t, "Иванов", *alarm.AcknowledgedBy)
+	}
+	assert.Equal(t, models.EventAlarmAcknowledged, gotEvent)
+	assert.Equal(t, "Проверил датчик", gotComment.Body)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcknowledgeAlarmTwice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE alarms SET acknowledged_at").
+		WithArgs(4, "Петров").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	body, _ := json.Marshal(map[string]interface{}{"alarm_id": 4, "user": "Петров"})
+	req := httptest.NewRequest("POST", "/api/alarms/ack", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	called := false
+	api.AcknowledgeAlarm(sqlxDB, func(string, models.Alarm, models.AlarmComment) { called = true })(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.False(t, called)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
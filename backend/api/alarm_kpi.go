@@ -0,0 +1,107 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"realtime-app/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Период отчета по умолчанию
+const defaultKPISpan = 7 * 24 * time.Hour
+
+// GetAlarmKPI возвращает показатели тревог за период ?from=&to=: количество по
+// датчикам и оборудованию, 10 самых частых тревог, среднее время квитирования
+// и снятия, частоту тревог в час. Учитываются только тревоги по границам.
+func GetAlarmKPI(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		from, to, err := parseTimeRange(r, defaultKPISpan)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		kpi := models.AlarmKPI{From: from, To: to}
+		if err := loadAlarmKPI(db, &kpi); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, kpi)
+	}
+}
+
+func loadAlarmKPI(db *sqlx.DB, kpi *models.AlarmKPI) error {
+	// Тревоги по прогнозу - предупреждения, в показатели они не входят
+	const period = "raised_at >= $1 AND raised_at < $2 AND kind = '" + models.AlarmKindLimit + "'"
+
+	kpi.BySensor = []models.AlarmCount{}
+	if err := db.Select(&kpi.BySensor, `
+		SELECT type, COUNT(*) AS count FROM alarms
+		WHERE `+period+`
+		GROUP BY type ORDER BY count DESC, type`,
+		kpi.From, kpi.To); err != nil {
+		return err
+	}
+
+	kpi.ByEquipment = []models.AlarmCount{}
+	if err := db.Select(&kpi.ByEquipment, `
+		SELECT a.equipment_id, e.name AS equipment_name, COUNT(*) AS count
+		FROM alarms a
+		LEFT JOIN equipment e ON e.id = a.equipment_id
+		WHERE a.`+period+`
+		GROUP BY a.equipment_id, e.name ORDER BY count DESC`,
+		kpi.From, kpi.To); err != nil {
+		return err
+	}
+
+	kpi.TopFrequent = []models.AlarmCount{}
+	if err := db.Select(&kpi.TopFrequent, `
+		SELECT type, direction, COUNT(*) AS count FROM alarms
+		WHERE `+period+`
+		GROUP BY type, direction ORDER BY count DESC, type LIMIT 10`,
+		kpi.From, kpi.To); err != nil {
+		return err
+	}
+
+	var times struct {
+		Total int      `db:"total"`
+		MTTA  *float64 `db:"mtta"`
+		MTTC  *float64 `db:"mttc"`
+	}
+	if err := db.Get(&times, `
+		SELECT COUNT(*) AS total,
+		       AVG(EXTRACT(EPOCH FROM acknowledged_at - raised_at)) AS mtta,
+		       AVG(EXTRACT(EPOCH FROM cleared_at - raised_at)) AS mttc
+		FROM alarms
+		WHERE `+period,
+		kpi.From, kpi.To); err != nil {
+		return err
+	}
+	kpi.Total = times.Total
+	kpi.MeanTimeToAckSeconds = times.MTTA
+	kpi.MeanTimeToClearSeconds = times.MTTC
+
+	kpi.Hourly = []models.AlarmHourlyCount{}
+	if err := db.Select(&kpi.Hourly, `
+		SELECT h.hour, COUNT(a.id) AS count
+		FROM generate_series(date_trunc('hour', $1::timestamp), $2::timestamp, interval '1 hour') AS h(hour)
+		LEFT JOIN alarms a ON a.raised_at >= h.hour AND a.raised_at < h.hour + interval '1 hour'
+		                  AND a.`+period+`
+		GROUP BY h.hour ORDER BY h.hour`,
+		kpi.From, kpi.To); err != nil {
+		return err
+	}
+
+	hours := kpi.To.Sub(kpi.From).Hours()
+	kpi.AverageRatePerHour = math.Round(float64(kpi.Total)/hours*100) / 100
+	for _, h := range kpi.Hourly {
+		if h.Count > kpi.PeakRatePerHour {
+			kpi.PeakRatePerHour = h.Count
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAlarmKPI(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(2 * time.Hour)
+
+	mock.ExpectQuery("SELECT type, COUNT\\(\\*\\) AS count FROM alarms").
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "count"}).
+			AddRow("temperature", 3).
+			AddRow("pressure", 1))
+	mock.ExpectQuery("SELECT a.equipment_id, e.name AS equipment_name").
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"equipment_id", "equipment_name", "count"}).
+			AddRow(2, "Датчик температуры", 3).
+			AddRow(1, "Пресс 1", 1))
+	mock.ExpectQuery("SELECT type, direction, COUNT\\(\\*\\) AS count FROM alarms").
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "direction", "count"}).
+			AddRow("temperature", "high", 3).
+			AddRow("pressure", "low", 1))
+	mock.ExpectQuery("(?s)SELECT COUNT\\(\\*\\) AS total.*AND kind = 'limit'").
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"total", "mtta", "mttc"}).
+			AddRow(4, 90.0, nil))
+	mock.ExpectQuery("FROM generate_series").
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"hour", "count"}).
+			AddRow(from, 3).
+			AddRow(from.Add(time.Hour), 1))
+
+	req := httptest.NewRequest("GET", "/api/alarms/kpi?from=2024-03-01T00:00:00Z&to=2024-03-01T02:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	api.GetAlarmKPI(sqlxDB)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var kpi models.AlarmKPI
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &kpi))
+	assert.Equal(t, 4, kpi.Total)
+	assert.Len(t, kpi.BySensor, 2)
+	assert.Equal(t, "high", kpi.TopFrequent[0].Direction)
+	if assert.NotNil(t, kpi.MeanTimeToAckSeconds) {
+		assert.Equal(t, 90.0, *kpi.MeanTimeToAckSeconds)
+	}
+	assert.Nil(t, kpi.MeanTimeToClearSeconds)
+	assert.Equal(t, 2.0, kpi.AverageRatePerHour)
+	assert.Equal(t, 3, kpi.PeakRatePerHour)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAlarmKPIInvalidRange(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/alarms/kpi?from=2024-03-02&to=2024-03-01", nil)
+	w := httptest.NewRecorder()
+
+	api.GetAlarmKPI(nil)(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
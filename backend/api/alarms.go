@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"realtime-app/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetAlarms возвращает тревоги, по умолчанию последние 100; ?state=active|cleared фильтрует по состоянию
+func GetAlarms(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		alarms := []models.Alarm{}
+		var err error
+		if state := r.URL.Query().Get("state"); state != "" {
+			err = db.Select(&alarms, "SELECT * FROM alarms WHERE state = $1 ORDER BY raised_at DESC LIMIT 100", state)
+		} else {
+			err = db.Select(&alarms, "SELECT * FROM alarms ORDER BY raised_at DESC LIMIT 100")
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, alarms)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/validation"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Окно выборки аномалий по умолчанию и предел числа записей
+const (
+	defaultAnomalySpan = 24 * time.Hour
+	maxAnomalies       = 1000
+)
+
+// GetAnomalies возвращает аномалии за ?from=&to= (по умолчанию последние
+// сутки), новые первыми; ?type= и ?equipment_id= сужают выборку
+func GetAnomalies(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		query := r.URL.Query()
+		from, to, err := parseTimeRange(r, defaultAnomalySpan)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, validation.CodeInvalidRange, "from", err.Error())
+			return
+		}
+		var sensorType *string
+		if v := query.Get("type"); v != "" {
+			if err := validation.SensorType("type", v); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			sensorType = &v
+		}
+		equipmentID, err := parseEquipmentID(query.Get("equipment_id"))
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		anomalies := []models.Anomaly{}
+		if err := db.Select(&anomalies, `
+			SELECT * FROM anomalies
+			WHERE detected_at >= $1 AND detected_at < $2
+			  AND ($3::text IS NULL OR type = $3)
+			  AND ($4::int IS NULL OR equipment_id = $4)
+			ORDER BY detected_at DESC, id DESC
+			LIMIT $5`,
+			from, to, sensorType, equipmentID, maxAnomalies); err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		jsonResponse(w, anomalies)
+	}
+}
@@ -0,0 +1,33 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAnomaliesMethods(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	handler := api.GetAnomalies(sqlx.NewDb(db, "sqlmock"))
+	for method, status := range map[string]int{
+		"OPTIONS": http.StatusOK,
+		"POST":    http.StatusMethodNotAllowed,
+		"DELETE":  http.StatusMethodNotAllowed,
+	} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(method, "/api/anomalies", nil))
+		assert.Equal(t, status, w.Code, method)
+	}
+	// Запрос к БД не выполнялся
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"realtime-app/bundle"
+	"realtime-app/models"
+	"realtime-app/validation"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ExportBundle выгружает пороги и границы оборудования (?format=yaml|json)
+func ExportBundle(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		format, err := bundle.ParseFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		b, err := bundle.Export(db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		contentType := "application/x-yaml"
+		if format == bundle.FormatJSON {
+			contentType = "application/json"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf(`attachment; filename="thresholds-%s.%s"`, b.ExportedAt.Format("20060102-150405"), format))
+		if err := bundle.Encode(w, b, format); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// ImportBundle загружает выгрузку порогов. С ?dry_run=true только возвращает
+// план изменений. Формат берется из ?format= или заголовка Content-Type.
+func ImportBundle(db *sqlx.DB, thresholdCallback UpdateThresholdCallback, limitsCallback LimitsChangedCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		query := r.URL.Query()
+		formatName := query.Get("format")
+		if formatName == "" && strings.Contains(r.Header.Get("Content-Type"), "json") {
+			formatName = bundle.FormatJSON
+		}
+		format, err := bundle.ParseFormat(formatName)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, validation.CodeInvalidValue, "format", err.Error())
+			return
+		}
+
+		b, err := bundle.Decode(r.Body, format)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, validation.CodeInvalidBody, "", err.Error())
+			return
+		}
+
+		if query.Get("dry_run") == "true" {
+			plan, err := bundle.Diff(db, b)
+			if err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			writeImportResult(w, plan, false)
+			return
+		}
+
+		change := models.ThresholdChange{User: query.Get("user"), Reason: query.Get("reason")}
+		if change.User == "" {
+			change.User = unknownUser
+		}
+		if change.Reason == "" {
+			change.Reason = "import " + b.ExportedAt.Format(time.RFC3339)
+		}
+
+		plan, saved, err := bundle.Apply(db, b, change)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to import bundle: "+err.Error())
+			return
+		}
+		if plan.Valid() {
+			for _, t := range saved {
+				thresholdCallback(t)
+			}
+			limitsCallback()
+		}
+		writeImportResult(w, plan, plan.Valid())
+	}
+}
+
+func writeImportResult(w http.ResponseWriter, plan bundle.Plan, applied bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !plan.Valid() {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"applied": applied,
+		"plan":    plan,
+	})
+}
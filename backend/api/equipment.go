@@ -3,7 +3,9 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	database "realtime-app/db"
 	"realtime-app/models"
+	"realtime-app/validation"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -20,9 +22,126 @@ func GetEquipmentList(db *sqlx.DB) http.HandlerFunc {
 	}
 }
 
+// CreateEquipment добавляет оборудование и применяет к нему шаблоны границ его типа
+func CreateEquipment(db *sqlx.DB, callback LimitsChangedCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		req := models.Equipment{Status: equipmentStatuses[0]}
+		if err := decodeBody(r, &req); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if err := validation.First(
+			validation.NotEmpty("name", req.Name),
+			validation.NotEmpty("type", req.Type),
+			validation.OneOf("status", req.Status, equipmentStatuses...),
+		); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var equipment models.Equipment
+		if err := tx.Get(&equipment,
+			"INSERT INTO equipment (name, type, status) VALUES ($1, $2, $3) RETURNING *",
+			req.Name, req.Type, req.Status); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to create equipment")
+			return
+		}
+
+		templates, err := database.ApplyTemplates(tx, nil, &equipment.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to apply templates: "+err.Error())
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeInternalError(w, err)
+			return
+		}
+
+		callback()
+		jsonResponse(w, map[string]interface{}{
+			"equipment": equipment,
+			"templates": templates,
+		})
+	}
+}
+
+// Допустимые статусы оборудования (CHECK в таблице equipment)
+var equipmentStatuses = []string{"Рабочее", "Неисправное", models.EquipmentUnderRepair}
+
+// UpdateEquipmentStatus меняет статус оборудования (Рабочее, Неисправное, В ремонте)
+func UpdateEquipmentStatus(db *sqlx.DB, callback func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		var req struct {
+			ID     int    `json:"id"`
+			Status string `json:"status"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if err := validation.First(
+			validation.Positive("id", req.ID),
+			validation.OneOf("status", req.Status, equipmentStatuses...),
+		); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		res, err := db.Exec("UPDATE equipment SET status = $1 WHERE id = $2", req.Status, req.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to update equipment status")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			writeError(w, http.StatusNotFound, codeNotFound, "id", "Equipment not found")
+			return
+		}
+
+		// Статус влияет на подавление тревог
+		callback()
+		jsonResponse(w, map[string]string{"status": "success"})
+	}
+}
+
 func jsonResponse(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+func setCORSHeaders(w http.ResponseWriter, methods string) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", methods)
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Max-Age", "86400")
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"realtime-app/validation"
+)
+
+// Коды ошибок, не связанных с проверкой полей
+const (
+	codeMethodNotAllowed = "method_not_allowed"
+	codeNotFound         = "not_found"
+	codeConflict         = "conflict"
+	codeInternal         = "internal"
+	codeNotEnoughData    = "not_enough_data"
+)
+
+// writeError отправляет ошибку в едином формате {code, field, message},
+// чтобы клиент мог подсветить поле, из-за которого запрос отклонен
+func writeError(w http.ResponseWriter, status int, code, field, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(validation.New(code, field, message))
+}
+
+// writeValidationError отправляет ошибку проверки с кодом 400;
+// прочие ошибки считаются внутренними
+func writeValidationError(w http.ResponseWriter, err error) {
+	var verr *validation.Error
+	if errors.As(err, &verr) {
+		writeError(w, http.StatusBadRequest, verr.Code, verr.Field, verr.Message)
+		return
+	}
+	writeInternalError(w, err)
+}
+
+func writeInternalError(w http.ResponseWriter, err error) {
+	writeError(w, http.StatusInternalServerError, codeInternal, "", err.Error())
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "", "Method not allowed")
+}
+
+// decodeBody разбирает JSON тело запроса. Ошибки разбора возвращаются как
+// validation.Error с именем поля, если его удалось определить.
+func decodeBody(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return validation.New(validation.CodeInvalidBody, "", "request body is empty")
+	case errors.As(err, &typeErr):
+		return validation.New(validation.CodeInvalidValue, typeErr.Field,
+			fmt.Sprintf("%s must be %s", typeErr.Field, typeErr.Type))
+	default:
+		return validation.New(validation.CodeInvalidBody, "", "invalid JSON: "+err.Error())
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"realtime-app/alarms"
+	"realtime-app/models"
+	"realtime-app/validation"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// EscalationPolicies: GET - список политик эскалации, POST - создание или изменение уровня
+func EscalationPolicies(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, POST, OPTIONS")
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			policies := []models.EscalationPolicy{}
+			if err := db.Select(&policies, "SELECT * FROM escalation_policies ORDER BY tier, type NULLS FIRST"); err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			jsonResponse(w, policies)
+		case http.MethodPost:
+			policy := models.EscalationPolicy{Enabled: true}
+			if err := decodeBody(r, &policy); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			checks := []error{
+				validation.NotEmpty("name", policy.Name),
+				validation.Positive("tier", policy.Tier),
+				validation.Positive("delay_seconds", policy.DelaySeconds),
+			}
+			if policy.Type != nil {
+				checks = append(checks, validation.SensorType("type", *policy.Type))
+			}
+			if err := validation.First(checks...); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+
+			var saved models.EscalationPolicy
+			if err := db.Get(&saved, `
+				INSERT INTO escalation_policies (name, type, tier, delay_seconds, enabled)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (tier, COALESCE(type, '')) DO UPDATE SET
+					name = EXCLUDED.name,
+					delay_seconds = EXCLUDED.delay_seconds,
+					enabled = EXCLUDED.enabled
+				RETURNING *`,
+				policy.Name, policy.Type, policy.Tier, policy.DelaySeconds, policy.Enabled); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to save escalation policy")
+				return
+			}
+			jsonResponse(w, saved)
+		default:
+			writeMethodNotAllowed(w)
+		}
+	}
+}
+
+// GetPendingEscalations возвращает запланированные эскалации неквитированных тревог
+func GetPendingEscalations(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		pending, err := alarms.PendingEscalations(db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, pending)
+	}
+}
+
+// GetEscalationHistory возвращает историю эскалаций; ?alarm_id= фильтрует по тревоге
+func GetEscalationHistory(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		history := []models.AlarmEscalation{}
+		var err error
+		if alarmID := r.URL.Query().Get("alarm_id"); alarmID != "" {
+			err = db.Select(&history, "SELECT * FROM alarm_escalations WHERE alarm_id = $1 ORDER BY escalated_at", alarmID)
+		} else {
+			err = db.Select(&history, "SELECT * FROM alarm_escalations ORDER BY escalated_at DESC LIMIT 200")
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, history)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"realtime-app/forecast"
+	"realtime-app/models"
+	"realtime-app/validation"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Пределы параметров прогноза
+const (
+	maxForecastHorizon = 4 * time.Hour
+	maxForecastWindow  = 24 * time.Hour
+	maxForecastStep    = time.Hour
+	maxForecastPoints  = 500
+)
+
+// GetForecast прогнозирует показания ?type= на ?horizon= минут вперед
+// (по умолчанию 30) с доверительным интервалом ?confidence= (0.8, 0.9, 0.95,
+// 0.99). История берется за ?window= минут (по умолчанию 120), усредненная по
+// ?step= (по умолчанию 1m). ?method=holt|linear. В ответе отмечается первый
+// ожидаемый выход за действующие границы датчика.
+func GetForecast(db *sqlx.DB, limits EffectiveLimitFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		query := r.URL.Query()
+		req := forecast.Request{
+			Type:       query.Get("type"),
+			Method:     query.Get("method"),
+			Window:     forecast.DefaultWindow,
+			Step:       forecast.DefaultStep,
+			Horizon:    forecast.DefaultHorizon,
+			Confidence: forecast.DefaultConfidence,
+		}
+		if req.Method == "" {
+			req.Method = models.ForecastHolt
+		}
+
+		var err error
+		if req.EquipmentID, err = parseEquipmentID(query.Get("equipment_id")); err == nil {
+			err = validation.First(
+				validation.SensorType("type", req.Type),
+				validation.OneOf("method", req.Method, models.ForecastHolt, models.ForecastLinear),
+			)
+		}
+		if err == nil && query.Get("horizon") != "" {
+			req.Horizon, err = parseMinutes("horizon", query.Get("horizon"), maxForecastHorizon)
+		}
+		if err == nil && query.Get("window") != "" {
+			req.Window, err = parseMinutes("window", query.Get("window"), maxForecastWindow)
+		}
+		if err == nil && query.Get("step") != "" {
+			if req.Step, err = parseBucket("step", query.Get("step")); err == nil && req.Step > maxForecastStep {
+				err = validation.New(validation.CodeOutOfRange, "step", "step must not exceed 1h")
+			}
+		}
+		if err == nil && query.Get("confidence") != "" {
+			req.Confidence, err = parseConfidence(query.Get("confidence"))
+		}
+		if err == nil && req.Horizon/req.Step > maxForecastPoints {
+			err = validation.New(validation.CodeOutOfRange, "horizon",
+				fmt.Sprintf("horizon holds more than %d steps, use a larger step", maxForecastPoints))
+		}
+		if err == nil && req.Horizon < req.Step {
+			err = validation.New(validation.CodeOutOfRange, "horizon", "horizon must be at least one step")
+		}
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		var limit *models.Threshold
+		if l, ok := limits(req.Type, req.EquipmentID); ok {
+			limit = &l
+		}
+		f, err := forecast.Build(db, req, limit)
+		if errors.Is(err, forecast.ErrNotEnoughData) {
+			writeError(w, http.StatusUnprocessableEntity, codeNotEnoughData, "window", err.Error())
+			return
+		}
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		jsonResponse(w, f)
+	}
+}
+
+// parseMinutes разбирает целое число минут от 1 до max
+func parseMinutes(field, value string, max time.Duration) (time.Duration, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || time.Duration(n)*time.Minute > max {
+		return 0, validation.New(validation.CodeOutOfRange, field,
+			fmt.Sprintf("%s must be between 1 and %d minutes", field, int(max/time.Minute)))
+	}
+	return time.Duration(n) * time.Minute, nil
+}
+
+func parseConfidence(value string) (float64, error) {
+	c, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		for _, allowed := range forecast.Confidences {
+			if c == allowed {
+				return c, nil
+			}
+		}
+	}
+	return 0, validation.New(validation.CodeInvalidValue, "confidence", "confidence must be one of 0.8, 0.9, 0.95, 0.99")
+}
@@ -0,0 +1,78 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetForecastPredictsCrossing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	// Температура растет на 0.5 в минуту и подходит к верхней границе 35
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"bucket", "value"})
+	for i := 0; i <= 20; i++ {
+		rows.AddRow(start.Add(time.Duration(i)*time.Minute), 20+0.5*float64(i))
+	}
+	mock.ExpectQuery("FROM sensor_data").
+		WithArgs("temperature", (2 * time.Hour).Seconds(), nil, 60).
+		WillReturnRows(rows)
+
+	limits := func(sensorType string, equipmentID *int) (models.Threshold, bool) {
+		return models.Threshold{Type: sensorType, MinValue: 15, MaxValue: 35}, true
+	}
+	req := httptest.NewRequest("GET", "/api/sensor-data/forecast?type=temperature&method=linear&horizon=15", nil)
+	w := httptest.NewRecorder()
+
+	api.GetForecast(sqlx.NewDb(db, "sqlmock"), limits)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var f models.Forecast
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &f))
+	// Незаполненный последний интервал отброшен
+	assert.Equal(t, 20, f.Samples)
+	if assert.Len(t, f.Points, 15) {
+		assert.InDelta(t, 30.0, f.Points[0].Value, 0.01)
+		assert.LessOrEqual(t, f.Points[0].Lower, f.Points[0].Value)
+	}
+	if assert.NotNil(t, f.Crossing) {
+		assert.Equal(t, models.AlarmHigh, f.Crossing.Direction)
+		assert.Equal(t, start.Add(31*time.Minute), f.Crossing.At)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetForecastNotEnoughData(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM sensor_data").
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "value"}).AddRow(time.Now(), 50.0))
+
+	limits := func(string, *int) (models.Threshold, bool) { return models.Threshold{}, false }
+	req := httptest.NewRequest("GET", "/api/sensor-data/forecast?type=humidity", nil)
+	w := httptest.NewRecorder()
+
+	api.GetForecast(sqlx.NewDb(db, "sqlmock"), limits)(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"not_enough_data"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/validation"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// LimitsChangedCallback вызывается после изменения границ по оборудованию
+type LimitsChangedCallback func()
+
+// GetEffectiveLimits возвращает действующие границы датчиков с учетом оборудования
+func GetEffectiveLimits(limits func() []models.EffectiveLimit) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+		jsonResponse(w, limits())
+	}
+}
+
+// EquipmentLimits задает (POST) или удаляет (DELETE) границы параметра
+// оборудования для типа датчика. Без собственных границ действует общий порог типа.
+func EquipmentLimits(db *sqlx.DB, callback LimitsChangedCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, DELETE, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		// Границы - указатели, чтобы отличить пропущенное поле от нуля
+		var req struct {
+			EquipmentID int      `json:"equipment_id"`
+			Type        string   `json:"type"`
+			MinValue    *float64 `json:"min_value"`
+			MaxValue    *float64 `json:"max_value"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if req.EquipmentID == 0 {
+			writeValidationError(w, validation.Required("equipment_id"))
+			return
+		}
+		if err := validation.SensorType("type", req.Type); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			res, err := db.Exec(`
+				DELETE FROM reference_parameters
+				WHERE id_param IN (
+					SELECT id FROM process_parameters WHERE id_equipment = $1 AND sensor_type = $2
+				)`,
+				req.EquipmentID, req.Type)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to delete limit")
+				return
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				writeError(w, http.StatusNotFound, codeNotFound, "type", "Limit not found")
+				return
+			}
+			callback()
+			jsonResponse(w, map[string]string{"status": "success"})
+			return
+		}
+
+		if req.MinValue == nil {
+			writeValidationError(w, validation.Required("min_value"))
+			return
+		}
+		if req.MaxValue == nil {
+			writeValidationError(w, validation.Required("max_value"))
+			return
+		}
+		limit := models.Threshold{Type: req.Type, MinValue: *req.MinValue, MaxValue: *req.MaxValue}
+		if err := validation.Threshold(limit); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		defer tx.Rollback()
+
+		// Параметр процесса создается, если у оборудования его еще нет
+		sensorType, _ := models.ParseSensorType(req.Type)
+		name, units := sensorType.Parameter()
+		var paramID int
+		if err := tx.Get(&paramID, `
+			INSERT INTO process_parameters (id_equipment, name, units, sensor_type)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (id_equipment, sensor_type) DO UPDATE SET sensor_type = EXCLUDED.sensor_type
+			RETURNING id`,
+			req.EquipmentID, name, units, req.Type); err != nil {
+			writeError(w, http.StatusBadRequest, validation.CodeInvalidValue, "equipment_id", "Failed to resolve process parameter")
+			return
+		}
+
+		var refParam models.ReferenceParameter
+		if err := tx.Get(&refParam, `
+			INSERT INTO reference_parameters (id_param, min_value, max_value)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (id_param) DO UPDATE SET
+				min_value = EXCLUDED.min_value,
+				max_value = EXCLUDED.max_value,
+				template_id = NULL
+			RETURNING *`,
+			paramID, limit.MinValue, limit.MaxValue); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to save limit")
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			writeInternalError(w, err)
+			return
+		}
+
+		callback()
+		jsonResponse(w, refParam)
+	}
+}
@@ -0,0 +1,85 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEquipmentLimitsRequiresBothBounds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	handler := api.EquipmentLimits(sqlx.NewDb(db, "sqlmock"), func() {})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/limits",
+		strings.NewReader(`{"equipment_id":2,"type":"temperature","max_value":50}`)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var body map[string]string
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, "min_value", body["field"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEquipmentLimitsCreatesNamedParameter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	called := false
+	handler := api.EquipmentLimits(sqlx.NewDb(db, "sqlmock"), func() { called = true })
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO process_parameters").
+		WithArgs(2, "Температура", "°C", "temperature").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery("INSERT INTO reference_parameters").
+		WithArgs(7, 0.0, 50.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "id_param", "min_value", "max_value"}).AddRow(1, 7, 0.0, 50.0))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/limits",
+		strings.NewReader(`{"equipment_id":2,"type":"temperature","min_value":0,"max_value":50}`)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, called)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEquipmentLimitsDeleteMissing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	called := false
+	handler := api.EquipmentLimits(sqlx.NewDb(db, "sqlmock"), func() { called = true })
+
+	mock.ExpectExec("DELETE FROM reference_parameters").
+		WithArgs(2, "temperature").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("DELETE", "/api/limits",
+		strings.NewReader(`{"equipment_id":2,"type":"temperature"}`)))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.False(t, called)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
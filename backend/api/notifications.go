@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/validation"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Наибольший уровень эскалации, на который можно подписать канал
+const maxChannelTier = 10
+
+// NotificationChannels: GET - список каналов оповещения, POST - создание или изменение канала по имени
+func NotificationChannels(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, POST, OPTIONS")
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			channels := []models.NotificationChannel{}
+			if err := db.Select(&channels, "SELECT * FROM notification_channels ORDER BY id"); err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			jsonResponse(w, channels)
+		case http.MethodPost:
+			ch := models.NotificationChannel{Enabled: true}
+			if err := decodeBody(r, &ch); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			if err := validation.First(
+				validation.NotEmpty("name", ch.Name),
+				validation.OneOf("kind", ch.Kind, models.ChannelWebhook, models.ChannelEmail),
+				validation.NotEmpty("target", ch.Target),
+				validation.Between("tier", ch.Tier, 0, maxChannelTier),
+			); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+
+			var saved models.NotificationChannel
+			if err := db.Get(&saved, `
+				INSERT INTO notification_channels (name, kind, target, enabled, tier)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (name) DO UPDATE SET
+					kind = EXCLUDED.kind,
+					target = EXCLUDED.target,
+					enabled = EXCLUDED.enabled,
+					tier = EXCLUDED.tier
+				RETURNING *`,
+				ch.Name, ch.Kind, ch.Target, ch.Enabled, ch.Tier); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to save channel")
+				return
+			}
+			jsonResponse(w, saved)
+		default:
+			writeMethodNotAllowed(w)
+		}
+	}
+}
+
+// GetNotificationLog возвращает журнал доставки оповещений; ?alarm_id= фильтрует по тревоге
+func GetNotificationLog(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		entries := []models.NotificationLog{}
+		var err error
+		if alarmID := r.URL.Query().Get("alarm_id"); alarmID != "" {
+			err = db.Select(&entries, "SELECT * FROM notification_log WHERE alarm_id = $1 ORDER BY id DESC", alarmID)
+		} else {
+			err = db.Select(&entries, "SELECT * FROM notification_log ORDER BY id DESC LIMIT 200")
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, entries)
+	}
+}
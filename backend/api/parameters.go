@@ -1,9 +1,9 @@
 package api
 
 import (
-	"encoding/json"
 	"net/http"
 	"realtime-app/models"
+	"realtime-app/validation"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -24,14 +24,27 @@ func GetCurrentParameters(db *sqlx.DB) http.HandlerFunc {
 
 func UpdateReferenceParameter(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("Access-Control-Max-Age", "86400")
+		setCORSHeaders(w, "POST, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
 
 		var refParam models.ReferenceParameter
-		if err := json.NewDecoder(r.Body).Decode(&refParam); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		if err := decodeBody(r, &refParam); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if err := validation.First(
+			validation.Positive("paramId", refParam.ParamID),
+			validation.Bounds("min", "max", refParam.Min, refParam.Max),
+		); err != nil {
+			writeValidationError(w, err)
 			return
 		}
 
@@ -40,11 +53,12 @@ func UpdateReferenceParameter(db *sqlx.DB) http.HandlerFunc {
                           VALUES ($1, $2, $3)
                           ON CONFLICT (id_param) DO UPDATE SET
                           min_value = EXCLUDED.min_value,
-                          max_value = EXCLUDED.max_value`,
+                          max_value = EXCLUDED.max_value,
+                          template_id = NULL`,
 			refParam.ParamID, refParam.Min, refParam.Max)
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeInternalError(w, err)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
@@ -0,0 +1,34 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateReferenceParameterMethods(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	handler := api.UpdateReferenceParameter(sqlx.NewDb(db, "sqlmock"))
+
+	// Предварительный запрос браузера
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("OPTIONS", "/api/parameters/reference", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "POST, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/api/parameters/reference", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/profiles"
+	"realtime-app/validation"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ThresholdProfiles: GET - профили с порогами и расписанием,
+// POST - создание или замена профиля (name, description, values).
+// Новые значения активного профиля сразу применяются к порогам;
+// ?user= - автор изменения в истории порогов.
+func ThresholdProfiles(db *sqlx.DB, callback UpdateThresholdCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, POST, OPTIONS")
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			var ids []int
+			if err := db.Select(&ids, "SELECT id FROM threshold_profiles ORDER BY name"); err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			result := []models.ThresholdProfile{}
+			for _, id := range ids {
+				profile, err := profiles.Load(db, id)
+				if err != nil {
+					writeInternalError(w, err)
+					return
+				}
+				result = append(result, profile)
+			}
+			jsonResponse(w, result)
+		case http.MethodPost:
+			var req models.ThresholdProfile
+			if err := decodeBody(r, &req); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			if err := validation.NotEmpty("name", req.Name); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			if len(req.Values) == 0 {
+				writeValidationError(w, validation.Required("values"))
+				return
+			}
+			for i, v := range req.Values {
+				if err := validation.Threshold(models.Threshold{Type: v.Type, MinValue: v.MinValue, MaxValue: v.MaxValue}); err != nil {
+					writeValidationError(w, validation.Prefix(err, fmt.Sprintf("values[%d]", i)))
+					return
+				}
+			}
+
+			tx, err := db.Beginx()
+			if err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			defer tx.Rollback()
+
+			var profileID int
+			if err := tx.Get(&profileID, `
+				INSERT INTO threshold_profiles (name, description)
+				VALUES ($1, $2)
+				ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description
+				RETURNING id`,
+				req.Name, req.Description); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to save profile")
+				return
+			}
+			if _, err := tx.Exec("DELETE FROM threshold_profile_values WHERE profile_id = $1", profileID); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to save profile")
+				return
+			}
+			for _, v := range req.Values {
+				if _, err := tx.Exec(`
+					INSERT INTO threshold_profile_values (profile_id, type, min_value, max_value)
+					VALUES ($1, $2, $3, $4)`,
+					profileID, v.Type, v.MinValue, v.MaxValue); err != nil {
+					writeError(w, http.StatusBadRequest, validation.CodeInvalidValue, "values", "Failed to save profile values")
+					return
+				}
+			}
+
+			profile, err := profiles.Load(tx, profileID)
+			if err != nil {
+				writeInternalError(w, err)
+				return
+			}
+
+			// Блокировка строки не дает планировщику сменить профиль до фиксации
+			var activeID *int
+			if err := tx.Get(&activeID, "SELECT profile_id FROM active_threshold_profile WHERE id = 1 FOR UPDATE"); err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			var applied []models.Threshold
+			if activeID != nil && *activeID == profileID {
+				user := r.URL.Query().Get("user")
+				if user == "" {
+					user = unknownUser
+				}
+				if applied, err = profiles.Apply(tx, profile, user); err != nil {
+					writeInternalError(w, err)
+					return
+				}
+			}
+
+			if err := tx.Commit(); err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			for _, t := range applied {
+				callback(t)
+			}
+			jsonResponse(w, profile)
+		default:
+			writeMethodNotAllowed(w)
+		}
+	}
+}
+
+// ProfileSchedule добавляет (POST) или удаляет (DELETE ?id=) интервал расписания профиля
+func ProfileSchedule(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, DELETE, OPTIONS")
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				writeValidationError(w, validation.Required("id"))
+				return
+			}
+			if _, err := db.Exec("DELETE FROM threshold_profile_schedule WHERE id = $1", id); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to delete schedule entry")
+				return
+			}
+			jsonResponse(w, map[string]string{"status": "success"})
+		case http.MethodPost:
+			entry := models.ProfileSchedule{Weekdays: 127}
+			if err := decodeBody(r, &entry); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			if err := validation.First(
+				validation.Positive("profile_id", entry.ProfileID),
+				validation.Between("weekdays", entry.Weekdays, 1, 127),
+				validateClock("start_time", entry.StartTime),
+				validateClock("end_time", entry.EndTime),
+			); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			if entry.StartTime == entry.EndTime {
+				writeError(w, http.StatusBadRequest, validation.CodeInvalidRange, "end_time", "end_time must differ from start_time")
+				return
+			}
+
+			var saved models.ProfileSchedule
+			if err := db.Get(&saved, `
+				INSERT INTO threshold_profile_schedule
+					(profile_id, weekdays, start_time, end_time, priority, valid_from, valid_to)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				RETURNING *`,
+				entry.ProfileID, entry.Weekdays, entry.StartTime, entry.EndTime, entry.Priority,
+				entry.ValidFrom, entry.ValidTo); err != nil {
+				writeError(w, http.StatusBadRequest, validation.CodeInvalidValue, "profile_id", "Failed to save schedule entry")
+				return
+			}
+			jsonResponse(w, saved)
+		default:
+			writeMethodNotAllowed(w)
+		}
+	}
+}
+
+// GetActiveProfile возвращает активный профиль порогов (null, если не задан)
+func GetActiveProfile(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		var profileID *int
+		if err := db.Get(&profileID, "SELECT profile_id FROM active_threshold_profile WHERE id = 1"); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if profileID == nil {
+			jsonResponse(w, nil)
+			return
+		}
+
+		profile, err := profiles.Load(db, *profileID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, profile)
+	}
+}
+
+// validateClock проверяет время суток ЧЧ:ММ или ЧЧ:ММ:СС
+func validateClock(field, value string) error {
+	if value == "" {
+		return validation.Required(field)
+	}
+	if _, err := time.Parse("15:04", value); err == nil {
+		return nil
+	}
+	if _, err := time.Parse("15:04:05", value); err == nil {
+		return nil
+	}
+	return validation.New(validation.CodeInvalidValue, field, field+" must be HH:MM")
+}
@@ -0,0 +1,74 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholdProfilesReappliesActiveProfile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	var applied []models.Threshold
+	handler := api.ThresholdProfiles(sqlx.NewDb(db, "sqlmock"), func(th models.Threshold) {
+		applied = append(applied, th)
+	})
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO threshold_profiles").
+		WithArgs("night", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("DELETE FROM threshold_profile_values").
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO threshold_profile_values").
+		WithArgs(2, "temperature", 15.0, 45.0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT \\* FROM threshold_profiles").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "created_at"}).
+			AddRow(2, "night", "", now))
+	mock.ExpectQuery("SELECT \\* FROM threshold_profile_values").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"profile_id", "type", "min_value", "max_value"}).
+			AddRow(2, "temperature", 15.0, 45.0))
+	mock.ExpectQuery("SELECT \\* FROM threshold_profile_schedule").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT profile_id FROM active_threshold_profile").
+		WillReturnRows(sqlmock.NewRows([]string{"profile_id"}).AddRow(2))
+	mock.ExpectQuery("SELECT min_value, max_value, version FROM thresholds").
+		WithArgs("temperature").
+		WillReturnRows(sqlmock.NewRows([]string{"min_value", "max_value", "version"}).AddRow(20.0, 40.0, 3))
+	mock.ExpectQuery("INSERT INTO thresholds").
+		WithArgs("temperature", 15.0, 45.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "min_value", "max_value", "version", "created_at", "updated_at"}).
+			AddRow(1, "temperature", 15.0, 45.0, 4, now, now))
+	mock.ExpectExec("INSERT INTO threshold_history").
+		WithArgs("temperature", 20.0, 40.0, 15.0, 45.0, "operator", `profile "night"`, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("POST", "/api/profiles?user=operator",
+		strings.NewReader(`{"name":"night","values":[{"type":"temperature","min_value":15,"max_value":45}]}`)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Len(t, applied, 1) {
+		assert.Equal(t, 4, applied[0].Version)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// parseTimeRange читает параметры from и to (RFC3339 или ГГГГ-ММ-ДД).
+// Если параметр не задан, to - текущий момент, from - to минус defaultSpan.
+// Время возвращается в UTC: колонки TIMESTAMP в БД хранят время сервера БД (UTC).
+func parseTimeRange(r *http.Request, defaultSpan time.Duration) (time.Time, time.Time, error) {
+	to := time.Now()
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %v", err)
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultSpan)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %v", err)
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	return from.UTC(), to.UTC(), nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/retention"
+	"realtime-app/rollup"
+	"realtime-app/validation"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Допустимая длина интервала агрегации
+const (
+	minAggregateBucket = time.Second
+	maxAggregateBucket = 24 * time.Hour
+)
+
+// Предел числа интервалов на датчик, чтобы ответ оставался пригодным для графика
+const maxAggregateBuckets = 5000
+
+// Без ?bucket= выбирается наименьший интервал из autoBuckets,
+// при котором на диапазон приходится не больше autoBucketTarget точек
+const autoBucketTarget = 500
+
+var autoBuckets = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// GetSensorAggregate возвращает min/max/avg/count/first/last показаний по
+// интервалам ?bucket= (1s…1d, например 30s, 5m, 1h, 1d) за ?from=&to=
+// (по умолчанию последние сутки). Без ?bucket= интервал подбирается по длине
+// диапазона. ?type= - один или несколько датчиков через запятую,
+// ?equipment_id= сужает выборку до оборудования. Если интервал кратен минуте,
+// часу или суткам, данные читаются из сверток, а не из sensor_data. Уровни,
+// данные которых за from удалены по сроку хранения policy, пропускаются.
+func GetSensorAggregate(db *sqlx.DB, policy retention.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		query := r.URL.Query()
+		from, to, err := parseTimeRange(r, defaultSensorDataSpan)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, validation.CodeInvalidRange, "from", err.Error())
+			return
+		}
+
+		types, err := parseSensorTypes(query.Get("type"))
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		bucket := autoBucket(to.Sub(from))
+		explicit := query.Get("bucket") != ""
+		if explicit {
+			if bucket, err = parseBucket("bucket", query.Get("bucket")); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+		}
+		bucket, res, err := resolveBucket(bucket, explicit, from, policy)
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if to.Sub(from)/bucket > maxAggregateBuckets {
+			writeError(w, http.StatusBadRequest, validation.CodeOutOfRange, "bucket",
+				fmt.Sprintf("range holds more than %d buckets, use a larger bucket", maxAggregateBuckets))
+			return
+		}
+
+		equipmentID, err := parseEquipmentID(query.Get("equipment_id"))
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		sqlQuery, args, resolution := bucketsQuery(types, from, to, bucket, equipmentID, res)
+		result := models.SensorAggregate{
+			From:          from,
+			To:            to,
+			BucketSeconds: int(bucket / time.Second),
+			Resolution:    resolution,
+			Series:        map[string][]models.SensorBucket{},
+		}
+		for _, t := range types {
+			result.Series[t] = []models.SensorBucket{}
+		}
+
+		buckets := []models.SensorBucket{}
+		if err := db.Select(&buckets, sqlQuery, args...); err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		for _, b := range buckets {
+			result.Series[b.Type] = append(result.Series[b.Type], b)
+		}
+		jsonResponse(w, result)
+	}
+}
+
+// resolveBucket выбирает источник интервалов длиной bucket за период с from:
+// самую крупную свертку, на шаг которой делится bucket, или sensor_data (nil),
+// пропуская уровни, данные которых за from удалены по сроку хранения.
+// Если ни один такой уровень данные за from не хранит, автоматически
+// выбранный интервал увеличивается до кратного шагу сохранившейся свертки,
+// а заданный явно отклоняется.
+func resolveBucket(bucket time.Duration, explicit bool, from time.Time, policy retention.Policy) (time.Duration, *rollup.Resolution, error) {
+	now := time.Now()
+	if res, ok := pickResolution(bucket, from, now, policy); ok {
+		return bucket, res, nil
+	}
+
+	var kept *rollup.Resolution
+	for i := range rollup.Resolutions {
+		if policy.Keeps(rollup.Resolutions[i].Name, from, now) {
+			kept = &rollup.Resolutions[i]
+			break
+		}
+	}
+	if kept == nil {
+		return 0, nil, validation.New(validation.CodeOutOfRange, "from", "data for this range is no longer kept")
+	}
+	if explicit {
+		return 0, nil, validation.New(validation.CodeOutOfRange, "bucket",
+			fmt.Sprintf("data for this range is kept only at %s resolution, use a bucket that is a multiple of %s", kept.Name, kept.Name))
+	}
+	// Сутки кратны шагу любой свертки, поэтому интервал найдется
+	for _, b := range autoBuckets {
+		if b >= bucket && b%kept.Step == 0 {
+			bucket = b
+			break
+		}
+	}
+	return bucket, kept, nil
+}
+
+// pickResolution - rollup.For с учетом сроков хранения; ok = false, если ни
+// один уровень, из которого собираются интервалы длиной bucket, данные за from
+// не хранит
+func pickResolution(bucket time.Duration, from, now time.Time, policy retention.Policy) (*rollup.Resolution, bool) {
+	for i := len(rollup.Resolutions) - 1; i >= 0; i-- {
+		res := &rollup.Resolutions[i]
+		if bucket%res.Step == 0 && policy.Keeps(res.Name, from, now) {
+			return res, true
+		}
+	}
+	return nil, policy.Keeps(rollup.Raw, from, now)
+}
+
+// bucketsQuery строит запрос интервалов длиной bucket из свертки res
+// (nil - из sensor_data) и возвращает его вместе с аргументами и именем
+// источника данных
+func bucketsQuery(types []string, from, to time.Time, bucket time.Duration, equipmentID *int, res *rollup.Resolution) (string, []interface{}, string) {
+	bucketSeconds := int(bucket / time.Second)
+	if res != nil {
+		query, args := rollupBucketsQuery(*res, types, from, to, bucketSeconds, equipmentID)
+		return query, args, res.Name
+	}
+	query, args := sensorBucketsQuery(types, from, to, bucketSeconds, equipmentID)
+	return query, args, rollup.Raw
+}
+
+// sensorBucketsQuery сводит показания в интервалы, отсчитываемые от начала эпохи,
+// поэтому границы интервалов не зависят от from
+func sensorBucketsQuery(types []string, from, to time.Time, bucketSeconds int, equipmentID *int) (string, []interface{}) {
+	return `
+		SELECT type, bucket,
+		       MIN(value) AS min, MAX(value) AS max, AVG(value) AS avg, COUNT(*) AS count,
+		       (ARRAY_AGG(value ORDER BY timestamp, id))[1] AS first,
+		       (ARRAY_AGG(value ORDER BY timestamp DESC, id DESC))[1] AS last
+		FROM (
+			SELECT type, value, timestamp, id,
+			       TIMESTAMP 'epoch' + FLOOR(EXTRACT(EPOCH FROM timestamp) / $4) * $4 * INTERVAL '1 second' AS bucket
+			FROM sensor_data
+			WHERE type = ANY($1) AND timestamp >= $2 AND timestamp < $3
+			  AND ($5::int IS NULL OR id_equipment = $5)
+		) s
+		GROUP BY type, bucket
+		ORDER BY type, bucket`,
+		[]interface{}{pq.Array(types), from, to, bucketSeconds, equipmentID}
+}
+
+// rollupBucketsQuery собирает интервалы из свертки res. Данные, которые еще
+// не свернуты, добираются из sensor_data. Интервалы свертки на краях
+// диапазона берутся целиком.
+func rollupBucketsQuery(res rollup.Resolution, types []string, from, to time.Time, bucketSeconds int, equipmentID *int) (string, []interface{}) {
+	return fmt.Sprintf(`
+		WITH mark AS (
+			SELECT COALESCE(rolled_up_to, '-infinity') AS rolled_up_to
+			FROM sensor_rollup_state WHERE resolution = $6
+		), src AS (
+			SELECT type, bucket AS at, 0 AS seq, min, max, sum, count, first, last
+			FROM %s, mark
+			WHERE type = ANY($1) AND bucket > $2 - $7 * INTERVAL '1 second'
+			  AND bucket < LEAST($3, mark.rolled_up_to)
+			  AND ($5::int IS NULL OR equipment_id = $5)
+			UNION ALL
+			SELECT type, timestamp, id, value, value, value, 1, value, value
+			FROM sensor_data, mark
+			WHERE type = ANY($1) AND timestamp >= GREATEST($2, mark.rolled_up_to) AND timestamp < $3
+			  AND ($5::int IS NULL OR COALESCE(id_equipment, 0) = $5)
+		)
+		SELECT type,
+		       TIMESTAMP 'epoch' + FLOOR(EXTRACT(EPOCH FROM at) / $4) * $4 * INTERVAL '1 second' AS bucket,
+		       MIN(min) AS min, MAX(max) AS max, SUM(sum) / SUM(count) AS avg, SUM(count) AS count,
+		       (ARRAY_AGG(first ORDER BY at, seq))[1] AS first,
+		       (ARRAY_AGG(last ORDER BY at DESC, seq DESC))[1] AS last
+		FROM src
+		GROUP BY 1, 2
+		ORDER BY 1, 2`, res.Table),
+		[]interface{}{pq.Array(types), from, to, bucketSeconds, equipmentID, res.Name, int(res.Step / time.Second)}
+}
+
+// autoBucket подбирает интервал для диапазона длиной span
+func autoBucket(span time.Duration) time.Duration {
+	for _, b := range autoBuckets {
+		if span/b <= autoBucketTarget {
+			return b
+		}
+	}
+	return maxAggregateBucket
+}
+
+// parseSensorTypes разбирает список датчиков через запятую
+func parseSensorTypes(value string) ([]string, error) {
+	if value == "" {
+		return nil, validation.Required("type")
+	}
+	var types []string
+	seen := map[string]bool{}
+	for _, t := range strings.Split(value, ",") {
+		t = strings.TrimSpace(t)
+		if err := validation.SensorType("type", t); err != nil {
+			return nil, err
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// parseBucket разбирает длину интервала: длительность Go (30s, 5m, 1h) или дни (1d)
+func parseBucket(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, validation.Required(field)
+	}
+	var bucket time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		bucket = time.Duration(n) * 24 * time.Hour
+	} else {
+		bucket, err = time.ParseDuration(value)
+	}
+	if err != nil || bucket%time.Second != 0 {
+		return 0, validation.New(validation.CodeInvalidValue, field, field+" must be a whole number of seconds, e.g. 30s, 5m, 1h or 1d")
+	}
+	if bucket < minAggregateBucket || bucket > maxAggregateBucket {
+		return 0, validation.New(validation.CodeOutOfRange, field, field+" must be between 1s and 1d")
+	}
+	return bucket, nil
+}
+
+// parseEquipmentID разбирает необязательный ?equipment_id=
+func parseEquipmentID(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return nil, validation.New(validation.CodeInvalidValue, "equipment_id", "equipment_id must be a positive integer")
+	}
+	return &id, nil
+}
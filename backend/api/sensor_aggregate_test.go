@@ -0,0 +1,147 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"realtime-app/retention"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSensorAggregate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	mock.ExpectQuery("FROM sensor_data").
+		WithArgs(pq.Array([]string{"temperature", "humidity"}), from, to, 30, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "bucket", "min", "max", "avg", "count", "first", "last"}).
+			AddRow("temperature", from, 20.1, 31.4, 25.2, 30, 20.5, 30.9).
+			AddRow("temperature", from.Add(30*time.Second), 21.0, 29.8, 24.7, 30, 30.9, 22.3))
+
+	req := httptest.NewRequest("GET", "/api/sensor-data/aggregate?type=temperature,humidity&bucket=30s&from=2024-03-01T00:00:00Z&to=2024-03-01T01:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	api.GetSensorAggregate(sqlx.NewDb(db, "sqlmock"), nil)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result models.SensorAggregate
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 30, result.BucketSeconds)
+	assert.Equal(t, "raw", result.Resolution)
+	if assert.Len(t, result.Series["temperature"], 2) {
+		assert.Equal(t, 31.4, result.Series["temperature"][0].Max)
+		assert.Equal(t, 22.3, result.Series["temperature"][1].Last)
+	}
+	// Датчик без показаний возвращается пустым рядом
+	assert.NotNil(t, result.Series["humidity"])
+	assert.Empty(t, result.Series["humidity"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSensorAggregateUsesRollup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 30)
+
+	// Месяц без ?bucket= - интервал 3 часа, собирается из часовой свертки
+	mock.ExpectQuery("FROM sensor_rollup_1h, mark").
+		WithArgs(pq.Array([]string{"pressure"}), from, to, 3*3600, nil, "1h", 3600).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "bucket", "min", "max", "avg", "count", "first", "last"}).
+			AddRow("pressure", from, 990.0, 1012.0, 1001.5, 10800, 995.0, 1003.0))
+
+	req := httptest.NewRequest("GET", "/api/sensor-data/aggregate?type=pressure&from=2024-03-01T00:00:00Z&to=2024-03-31T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	api.GetSensorAggregate(sqlx.NewDb(db, "sqlmock"), nil)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result models.SensorAggregate
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "1h", result.Resolution)
+	assert.Equal(t, 3*3600, result.BucketSeconds)
+	assert.Len(t, result.Series["pressure"], 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSensorAggregateRejectsTooManyBuckets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	for query, field := range map[string]string{
+		"type=temperature&bucket=1s&from=2024-03-01&to=2024-03-31": "bucket",
+		"type=temperature&bucket=2d":                               "bucket",
+		"type=temperature&bucket=500ms":                            "bucket",
+		"type=temperature,wind&bucket=1h":                          "type",
+	} {
+		req := httptest.NewRequest("GET", "/api/sensor-data/aggregate?"+query, nil)
+		w := httptest.NewRecorder()
+		api.GetSensorAggregate(sqlx.NewDb(db, "sqlmock"), nil)(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), `"field":"`+field+`"`, query)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSensorAggregateSkipsExpiredRollup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	day := 24 * time.Hour
+	policy := retention.Policy{"raw": 30 * day, "1m": 90 * day, "1h": 730 * day}
+	from := time.Now().UTC().Add(-100 * day).Truncate(day)
+	to := from.Add(day)
+
+	// Сутки без ?bucket= - 5 минут, но минутная свертка за этот период уже
+	// удалена: интервал увеличивается до часа и читается часовая свертка
+	mock.ExpectQuery("FROM sensor_rollup_1h, mark").
+		WithArgs(pq.Array([]string{"pressure"}), from, to, 3600, nil, "1h", 3600).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "bucket", "min", "max", "avg", "count", "first", "last"}).
+			AddRow("pressure", from, 990.0, 1012.0, 1001.5, 3600, 995.0, 1003.0))
+
+	req := httptest.NewRequest("GET", "/api/sensor-data/aggregate?type=pressure&from="+from.Format(time.RFC3339)+"&to="+to.Format(time.RFC3339), nil)
+	w := httptest.NewRecorder()
+	api.GetSensorAggregate(sqlx.NewDb(db, "sqlmock"), policy)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var result models.SensorAggregate
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "1h", result.Resolution)
+	assert.Equal(t, 3600, result.BucketSeconds)
+
+	// Явно заданный интервал не меняется, запрос отклоняется
+	req = httptest.NewRequest("GET", "/api/sensor-data/aggregate?type=pressure&bucket=5m&from="+from.Format(time.RFC3339)+"&to="+to.Format(time.RFC3339), nil)
+	w = httptest.NewRecorder()
+	api.GetSensorAggregate(sqlx.NewDb(db, "sqlmock"), policy)(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "kept only at 1h resolution")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/validation"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Окно выборки показаний по умолчанию
+const defaultSensorDataSpan = 24 * time.Hour
+
+// Размер страницы показаний по умолчанию и максимальный
+const (
+	defaultSensorDataLimit = 100
+	maxSensorDataLimit     = 1000
+)
+
+// sensorDataCursor - позиция последней отданной строки; строки упорядочены
+// по (timestamp, id), поэтому страницы не теряют и не дублируют показания
+// с одинаковым временем
+type sensorDataCursor struct {
+	Timestamp time.Time
+	ID        int
+}
+
+// RecentReadings - последние показания в памяти (recent.Buffer). Range
+// возвращает показания датчика за период от старых к новым или ok = false,
+// если период уже не целиком в памяти.
+type RecentReadings interface {
+	Range(sensorType string, from, to time.Time) ([]models.SensorData, bool)
+}
+
+// GetSensorData возвращает показания датчиков за ?from=&to= (по умолчанию
+// последние сутки). Фильтры: ?type=, ?equipment_id=; ?order=asc|desc
+// (по умолчанию desc), ?limit= (до 1000). Следующая страница - ?cursor=
+// из next_cursor предыдущего ответа при тех же фильтрах. Короткие периоды
+// по одному датчику отдаются из recent без запроса к БД. recent видит только
+// показания своего экземпляра, поэтому передается, только если этот экземпляр
+// единственный пишет показания; иначе nil.
+func GetSensorData(db *sqlx.DB, recent RecentReadings) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		query := r.URL.Query()
+		from, to, err := parseTimeRange(r, defaultSensorDataSpan)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, validation.CodeInvalidRange, "from", err.Error())
+			return
+		}
+
+		var sensorType *string
+		if v := query.Get("type"); v != "" {
+			if err := validation.SensorType("type", v); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			sensorType = &v
+		}
+
+		equipmentID, err := parseEquipmentID(query.Get("equipment_id"))
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		limit := defaultSensorDataLimit
+		if v := query.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxSensorDataLimit {
+				writeError(w, http.StatusBadRequest, validation.CodeOutOfRange, "limit",
+					fmt.Sprintf("limit must be between 1 and %d", maxSensorDataLimit))
+				return
+			}
+			limit = n
+		}
+
+		order := query.Get("order")
+		if order == "" {
+			order = "desc"
+		}
+		if err := validation.OneOf("order", order, "asc", "desc"); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		var cursorTime *time.Time
+		var cursorID *int
+		if v := query.Get("cursor"); v != "" {
+			cursor, err := decodeSensorDataCursor(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, validation.CodeInvalidValue, "cursor", "invalid cursor")
+				return
+			}
+			cursorTime, cursorID = &cursor.Timestamp, &cursor.ID
+		}
+
+		// Направление сравнения курсора совпадает с порядком сортировки
+		direction, after := "DESC", "<"
+		if order == "asc" {
+			direction, after = "ASC", ">"
+		}
+
+		// Лишняя строка показывает, что есть следующая страница
+		var items []models.SensorData
+		if recent != nil && sensorType != nil {
+			if readings, ok := recent.Range(*sensorType, from, to); ok {
+				items = pageReadings(readings, equipmentID, cursorTime, cursorID, order, limit+1)
+			}
+		}
+		if items == nil {
+			items = []models.SensorData{}
+			if err := db.Select(&items, fmt.Sprintf(`
+				SELECT * FROM sensor_data
+				WHERE timestamp >= $1 AND timestamp < $2
+				  AND ($3::text IS NULL OR type = $3)
+				  AND ($4::int IS NULL OR id_equipment = $4)
+				  AND ($5::timestamp IS NULL OR (timestamp, id) %s ($5, $6))
+				ORDER BY timestamp %s, id %s
+				LIMIT $7`, after, direction, direction),
+				from, to, sensorType, equipmentID, cursorTime, cursorID, limit+1); err != nil {
+				writeInternalError(w, err)
+				return
+			}
+		}
+
+		page := models.SensorDataPage{Items: items}
+		if len(items) > limit {
+			page.Items = items[:limit]
+			last := page.Items[limit-1]
+			next := encodeSensorDataCursor(sensorDataCursor{Timestamp: last.Timestamp, ID: last.ID})
+			page.NextCursor = &next
+		}
+		jsonResponse(w, page)
+	}
+}
+
+// pageReadings отбирает из показаний, упорядоченных от старых к новым,
+// страницу так же, как запрос к sensor_data в GetSensorData
+func pageReadings(readings []models.SensorData, equipmentID *int, cursorTime *time.Time, cursorID *int, order string, limit int) []models.SensorData {
+	items := []models.SensorData{}
+	for i := range readings {
+		r := readings[i]
+		if order == "desc" {
+			r = readings[len(readings)-1-i]
+		}
+		if equipmentID != nil && (r.EquipmentID == nil || *r.EquipmentID != *equipmentID) {
+			continue
+		}
+		if cursorTime != nil {
+			afterCursor := r.Timestamp.After(*cursorTime) || r.Timestamp.Equal(*cursorTime) && r.ID > *cursorID
+			if order == "desc" {
+				afterCursor = r.Timestamp.Before(*cursorTime) || r.Timestamp.Equal(*cursorTime) && r.ID < *cursorID
+			}
+			if !afterCursor {
+				continue
+			}
+		}
+		items = append(items, r)
+		if len(items) == limit {
+			break
+		}
+	}
+	return items
+}
+
+func encodeSensorDataCursor(c sensorDataCursor) string {
+	raw := c.Timestamp.Format(time.RFC3339Nano) + "," + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSensorDataCursor(value string) (sensorDataCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return sensorDataCursor{}, err
+	}
+	ts, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return sensorDataCursor{}, fmt.Errorf("malformed cursor")
+	}
+	var c sensorDataCursor
+	if c.Timestamp, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+		return sensorDataCursor{}, err
+	}
+	if c.ID, err = strconv.Atoi(id); err != nil {
+		return sensorDataCursor{}, err
+	}
+	return c, nil
+}
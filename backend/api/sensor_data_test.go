@@ -0,0 +1,166 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"realtime-app/recent"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSensorDataPagination(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	ts := from.Add(10 * time.Minute)
+	columns := []string{"id", "value", "type", "timestamp", "id_equipment"}
+
+	// Первая страница: запрошено 2 строки, получено 3 - есть следующая
+	mock.ExpectQuery("SELECT \\* FROM sensor_data").
+		WithArgs(from, to, "temperature", nil, nil, nil, 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(12, 25.5, "temperature", ts, 2).
+			AddRow(11, 25.1, "temperature", ts, 2).
+			AddRow(10, 24.8, "temperature", ts.Add(-time.Minute), 2))
+
+	req := httptest.NewRequest("GET", "/api/sensor-data?type=temperature&limit=2&from=2024-03-01T00:00:00Z&to=2024-03-01T01:00:00Z", nil)
+	w := httptest.NewRecorder()
+	api.GetSensorData(sqlxDB, nil)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page models.SensorDataPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Len(t, page.Items, 2)
+	if !assert.NotNil(t, page.NextCursor) {
+		return
+	}
+
+	// Вторая страница продолжается после последней отданной строки
+	mock.ExpectQuery("\\(timestamp, id\\) < \\(\\$5, \\$6\\)").
+		WithArgs(from, to, "temperature", nil, ts, 11, 3).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(10, 24.8, "temperature", ts.Add(-time.Minute), 2))
+
+	req = httptest.NewRequest("GET", "/api/sensor-data?type=temperature&limit=2&from=2024-03-01T00:00:00Z&to=2024-03-01T01:00:00Z&cursor="+*page.NextCursor, nil)
+	w = httptest.NewRecorder()
+	api.GetSensorData(sqlxDB, nil)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	page = models.SensorDataPage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Len(t, page.Items, 1)
+	assert.Nil(t, page.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSensorDataValidation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	for query, field := range map[string]string{
+		"type=wind":             "type",
+		"limit=5000":            "limit",
+		"order=sideways":        "order",
+		"cursor=bm90LWN1cnNvcg": "cursor",
+		"equipment_id=abc":      "equipment_id",
+	} {
+		req := httptest.NewRequest("GET", "/api/sensor-data?"+query, nil)
+		w := httptest.NewRecorder()
+		api.GetSensorData(sqlx.NewDb(db, "sqlmock"), nil)(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), `"field":"`+field+`"`, query)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSensorDataFromRecentBuffer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	// Буфер на 3 показания: после вытеснения первого полон с его времени
+	ts := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	buffer := recent.NewBuffer(3)
+	for i := 0; i < 4; i++ {
+		buffer.Add(models.SensorData{ID: i + 1, Value: float64(i), Type: "temperature", Timestamp: ts.Add(time.Duration(i) * time.Second)})
+	}
+
+	req := httptest.NewRequest("GET", "/api/sensor-data?type=temperature&limit=2&from="+ts.Add(time.Second).Format(time.RFC3339), nil)
+	w := httptest.NewRecorder()
+	api.GetSensorData(sqlx.NewDb(db, "sqlmock"), buffer)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page models.SensorDataPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	if assert.Len(t, page.Items, 2) {
+		assert.Equal(t, 4, page.Items[0].ID)
+		assert.Equal(t, 3, page.Items[1].ID)
+	}
+	assert.NotNil(t, page.NextCursor)
+
+	// Следующая страница тоже из буфера
+	req = httptest.NewRequest("GET", "/api/sensor-data?type=temperature&limit=2&from="+ts.Add(time.Second).Format(time.RFC3339)+"&cursor="+*page.NextCursor, nil)
+	w = httptest.NewRecorder()
+	api.GetSensorData(sqlx.NewDb(db, "sqlmock"), buffer)(w, req)
+
+	page = models.SensorDataPage{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, 2, page.Items[0].ID)
+	}
+	assert.Nil(t, page.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSensorDataFallsBackWhenBufferDoesNotCover(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	// Показание 1 вытеснено: более ранний период есть только в БД
+	ts := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	buffer := recent.NewBuffer(2)
+	for i := 0; i < 3; i++ {
+		buffer.Add(models.SensorData{ID: i + 1, Value: float64(i), Type: "temperature", Timestamp: ts.Add(time.Duration(i) * time.Second)})
+	}
+
+	from := ts.Add(-time.Hour)
+	mock.ExpectQuery("SELECT \\* FROM sensor_data").
+		WithArgs(from, sqlmock.AnyArg(), "temperature", nil, nil, nil, 101).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "type", "timestamp", "id_equipment"}).
+			AddRow(3, 2.0, "temperature", ts.Add(2*time.Second), nil).
+			AddRow(2, 1.0, "temperature", ts.Add(time.Second), nil).
+			AddRow(1, 0.0, "temperature", ts, nil))
+
+	req := httptest.NewRequest("GET", "/api/sensor-data?type=temperature&from="+from.Format(time.RFC3339), nil)
+	w := httptest.NewRecorder()
+	api.GetSensorData(sqlx.NewDb(db, "sqlmock"), buffer)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var page models.SensorDataPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Len(t, page.Items, 3)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
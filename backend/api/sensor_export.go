@@ -0,0 +1,244 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"realtime-app/export"
+	"realtime-app/models"
+	"realtime-app/retention"
+	"realtime-app/rollup"
+	"realtime-app/validation"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Колонки выгрузки показаний и интервалов; порядок - порядок по умолчанию
+var (
+	sensorExportColumns = map[string]func(models.SensorData) interface{}{
+		"timestamp":    func(d models.SensorData) interface{} { return d.Timestamp },
+		"type":         func(d models.SensorData) interface{} { return d.Type },
+		"equipment_id": func(d models.SensorData) interface{} { return intOrNil(d.EquipmentID) },
+		"value":        func(d models.SensorData) interface{} { return d.Value },
+		"id":           func(d models.SensorData) interface{} { return d.ID },
+	}
+	sensorExportDefault = []string{"timestamp", "type", "equipment_id", "value"}
+
+	bucketExportColumns = map[string]func(models.SensorBucket) interface{}{
+		"bucket": func(b models.SensorBucket) interface{} { return b.Bucket },
+		"type":   func(b models.SensorBucket) interface{} { return b.Type },
+		"min":    func(b models.SensorBucket) interface{} { return b.Min },
+		"max":    func(b models.SensorBucket) interface{} { return b.Max },
+		"avg":    func(b models.SensorBucket) interface{} { return b.Avg },
+		"count":  func(b models.SensorBucket) interface{} { return b.Count },
+		"first":  func(b models.SensorBucket) interface{} { return b.First },
+		"last":   func(b models.SensorBucket) interface{} { return b.Last },
+	}
+	bucketExportDefault = []string{"bucket", "type", "min", "max", "avg", "count", "first", "last"}
+)
+
+// ExportSensorData выгружает показания за ?from=&to= в ?format=csv|ndjson|xlsx
+// (по умолчанию csv). Фильтры те же, что у /api/sensor-data: ?type= (несколько
+// через запятую), ?equipment_id=. С ?bucket= выгружаются интервалы, как в
+// /api/sensor-data/aggregate. ?columns= - список и порядок колонок,
+// ?tz= - часовой пояс времени (например, Europe/Moscow), ?decimal=, -
+// десятичная запятая в CSV. Строки передаются клиенту по мере чтения из БД;
+// в XLSX сверх предела Excel (1 048 576 строк) начинается новый лист.
+// Периоды, сырые данные за которые удалены по сроку хранения policy,
+// выгружаются только интервалами.
+func ExportSensorData(db *sqlx.DB, policy retention.Policy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = export.FormatCSV
+		}
+		if err := validation.OneOf("format", format, export.Formats...); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		opts := export.Options{Location: time.UTC, Decimal: '.'}
+		if v := query.Get("tz"); v != "" {
+			loc, err := time.LoadLocation(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, validation.CodeInvalidValue, "tz", "unknown time zone "+v)
+				return
+			}
+			opts.Location = loc
+		}
+		if v := query.Get("decimal"); v != "" {
+			if err := validation.OneOf("decimal", v, ".", ","); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			opts.Decimal = rune(v[0])
+		}
+
+		from, to, err := parseTimeRange(r, defaultSensorDataSpan)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, validation.CodeInvalidRange, "from", err.Error())
+			return
+		}
+		var types []string
+		if v := query.Get("type"); v != "" {
+			if types, err = parseSensorTypes(v); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+		}
+		equipmentID, err := parseEquipmentID(query.Get("equipment_id"))
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		var columns []string
+		var sqlQuery string
+		var args []interface{}
+		var scan func(*sqlx.Rows) ([]interface{}, error)
+
+		if v := query.Get("bucket"); v != "" {
+			bucket, err := parseBucket("bucket", v)
+			if err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			if types == nil {
+				writeValidationError(w, validation.Required("type"))
+				return
+			}
+			if columns, err = parseColumns(query.Get("columns"), bucketExportDefault, func(c string) bool {
+				_, ok := bucketExportColumns[c]
+				return ok
+			}); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			bucket, res, err := resolveBucket(bucket, true, from, policy)
+			if err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			sqlQuery, args, _ = bucketsQuery(types, from, to, bucket, equipmentID, res)
+			scan = func(rows *sqlx.Rows) ([]interface{}, error) {
+				var b models.SensorBucket
+				if err := rows.StructScan(&b); err != nil {
+					return nil, err
+				}
+				values := make([]interface{}, len(columns))
+				for i, c := range columns {
+					values[i] = bucketExportColumns[c](b)
+				}
+				return values, nil
+			}
+		} else {
+			if !policy.Keeps(rollup.Raw, from, time.Now()) {
+				writeError(w, http.StatusBadRequest, validation.CodeOutOfRange, "from",
+					"raw readings for this range are no longer kept, use ?bucket=")
+				return
+			}
+			if columns, err = parseColumns(query.Get("columns"), sensorExportDefault, func(c string) bool {
+				_, ok := sensorExportColumns[c]
+				return ok
+			}); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			sqlQuery = `
+				SELECT id, value, type, timestamp, id_equipment FROM sensor_data
+				WHERE timestamp >= $1 AND timestamp < $2
+				  AND ($3::text[] IS NULL OR type = ANY($3))
+				  AND ($4::int IS NULL OR id_equipment = $4)
+				ORDER BY timestamp, id`
+			args = []interface{}{from, to, pq.Array(types), equipmentID}
+			scan = func(rows *sqlx.Rows) ([]interface{}, error) {
+				var d models.SensorData
+				if err := rows.StructScan(&d); err != nil {
+					return nil, err
+				}
+				values := make([]interface{}, len(columns))
+				for i, c := range columns {
+					values[i] = sensorExportColumns[c](d)
+				}
+				return values, nil
+			}
+		}
+
+		rows, err := db.Queryx(sqlQuery, args...)
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		defer rows.Close()
+
+		w.Header().Set("Content-Type", export.ContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="sensor-data-%s-%s.%s"`,
+			from.In(opts.Location).Format("20060102"), to.In(opts.Location).Format("20060102"), format))
+
+		// После начала передачи сменить статус уже нельзя: ошибки только в журнал
+		out, err := export.New(format, w, opts)
+		if err == nil {
+			err = writeExport(out, columns, rows, scan)
+		}
+		if err != nil {
+			log.Printf("Sensor data export failed: %v", err)
+		}
+	}
+}
+
+func writeExport(out export.Writer, columns []string, rows *sqlx.Rows, scan func(*sqlx.Rows) ([]interface{}, error)) error {
+	if err := out.Header(columns); err != nil {
+		return err
+	}
+	for rows.Next() {
+		values, err := scan(rows)
+		if err != nil {
+			return err
+		}
+		if err := out.Row(values); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// parseColumns разбирает ?columns= - список колонок через запятую
+func parseColumns(value string, defaults []string, known func(string) bool) ([]string, error) {
+	if value == "" {
+		return defaults, nil
+	}
+	var columns []string
+	for _, c := range strings.Split(value, ",") {
+		c = strings.TrimSpace(c)
+		if !known(c) {
+			return nil, validation.New(validation.CodeInvalidValue, "columns", "unknown column "+c)
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+func intOrNil(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,68 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportSensorDataCSV(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	mock.ExpectQuery("SELECT id, value, type, timestamp, id_equipment FROM sensor_data").
+		WithArgs(from, to, sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "value", "type", "timestamp", "id_equipment"}).
+			AddRow(1, 25.5, "temperature", from.Add(time.Minute), 2).
+			AddRow(2, 26.25, "temperature", from.Add(2*time.Minute), nil))
+
+	req := httptest.NewRequest("GET", "/api/sensor-data/export?type=temperature&columns=timestamp,value,equipment_id&decimal=,&from=2024-03-01T00:00:00Z&to=2024-03-01T01:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	api.ExportSensorData(sqlx.NewDb(db, "sqlmock"), nil)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "sensor-data-20240301-20240301.csv")
+	assert.Equal(t, "timestamp;value;equipment_id\n"+
+		"2024-03-01 00:01:00;25,5;2\n"+
+		"2024-03-01 00:02:00;26,25;\n", w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportSensorDataValidation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	for query, field := range map[string]string{
+		"format=pdf":                   "format",
+		"tz=Mars/Olympus":              "tz",
+		"decimal=x":                    "decimal",
+		"columns=timestamp,secret":     "columns",
+		"bucket=1h":                    "type",
+		"type=temperature&columns=avg": "columns",
+	} {
+		req := httptest.NewRequest("GET", "/api/sensor-data/export?"+query, nil)
+		w := httptest.NewRecorder()
+		api.ExportSensorData(sqlx.NewDb(db, "sqlmock"), nil)(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), `"field":"`+field+`"`, query)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/validation"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Предел числа промежутков в ответе
+const maxSensorGaps = 1000
+
+// ExpectedIntervalFunc возвращает ожидаемый период показаний датчика
+type ExpectedIntervalFunc func(sensorType string) time.Duration
+
+// GetSensorGaps возвращает промежутки без показаний длиннее ?min_gap=
+// (по умолчанию - ожидаемый период датчика) за ?from=&to= (по умолчанию
+// последние сутки). Промежутки от from до первого показания и от последнего
+// показания до to тоже учитываются. ?type= - один или несколько датчиков
+// через запятую, ?equipment_id= сужает выборку до оборудования.
+func GetSensorGaps(db *sqlx.DB, expected ExpectedIntervalFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		query := r.URL.Query()
+		from, to, err := parseTimeRange(r, defaultSensorDataSpan)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, validation.CodeInvalidRange, "from", err.Error())
+			return
+		}
+		types, err := parseSensorTypes(query.Get("type"))
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		var minGap time.Duration
+		if v := query.Get("min_gap"); v != "" {
+			if minGap, err = parseBucket("min_gap", v); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+		}
+		equipmentID, err := parseEquipmentID(query.Get("equipment_id"))
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		minGaps := make([]int64, len(types))
+		for i, t := range types {
+			gap := minGap
+			if gap == 0 {
+				gap = expected(t)
+			}
+			minGaps[i] = int64(gap / time.Second)
+		}
+
+		gaps := []models.SensorGap{}
+		if err := db.Select(&gaps, `
+			WITH limits AS (
+				SELECT * FROM UNNEST($1::text[], $4::bigint[]) AS l(type, min_gap)
+			), s AS (
+				SELECT type, id_equipment, timestamp,
+				       LAG(timestamp, 1, $2::timestamp) OVER (PARTITION BY type ORDER BY timestamp, id) AS prev
+				FROM sensor_data
+				WHERE type = ANY($1) AND timestamp >= $2 AND timestamp < $3
+				  AND ($5::int IS NULL OR id_equipment = $5)
+			), g AS (
+				SELECT type, id_equipment, prev AS start, timestamp AS "end" FROM s
+				UNION ALL
+				(SELECT DISTINCT ON (type) type, id_equipment, timestamp, LEAST($3::timestamp, LOCALTIMESTAMP)
+				 FROM s ORDER BY type, timestamp DESC)
+				UNION ALL
+				SELECT type, NULL, $2::timestamp, LEAST($3::timestamp, LOCALTIMESTAMP)
+				FROM limits WHERE NOT EXISTS (SELECT 1 FROM s WHERE s.type = limits.type)
+			)
+			SELECT g.type, g.id_equipment, g.start, g."end",
+			       EXTRACT(EPOCH FROM g."end" - g.start)::float8 AS seconds
+			FROM g JOIN limits USING (type)
+			WHERE g."end" - g.start > limits.min_gap * INTERVAL '1 second'
+			ORDER BY g.start, g.type
+			LIMIT $6`,
+			pq.Array(types), from, to, pq.Array(minGaps), equipmentID, maxSensorGaps); err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		jsonResponse(w, gaps)
+	}
+}
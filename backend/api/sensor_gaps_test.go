@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSensorGapsUsesExpectedInterval(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	start := from.Add(10 * time.Minute)
+	mock.ExpectQuery("LAG\\(timestamp").
+		WithArgs(pq.Array([]string{"temperature", "pressure"}), from, to, pq.Array([]int64{10, 300}), nil, 1000).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id_equipment", "start", "end", "seconds"}).
+			AddRow("temperature", 1, start, start.Add(90*time.Second), 90))
+
+	expected := func(sensorType string) time.Duration {
+		if sensorType == "pressure" {
+			return 5 * time.Minute
+		}
+		return 10 * time.Second
+	}
+	req := httptest.NewRequest("GET", "/api/sensor-data/gaps?type=temperature,pressure&from=2024-03-01T00:00:00Z&to=2024-03-01T01:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	api.GetSensorGaps(sqlx.NewDb(db, "sqlmock"), expected)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var gaps []models.SensorGap
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &gaps))
+	if assert.Len(t, gaps, 1) {
+		assert.Equal(t, "temperature", gaps[0].Type)
+		assert.Equal(t, 90.0, gaps[0].Seconds)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSensorGapsRejectsInvalidMinGap(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	req := httptest.NewRequest("GET", "/api/sensor-data/gaps?type=temperature&min_gap=500ms", nil)
+	w := httptest.NewRecorder()
+
+	api.GetSensorGaps(sqlx.NewDb(db, "sqlmock"), func(string) time.Duration { return time.Second })(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/validation"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Процентили по умолчанию
+var defaultStatsPercentiles = []float64{5, 25, 75, 95}
+
+// EffectiveLimitFunc возвращает действующие границы датчика с учетом оборудования
+type EffectiveLimitFunc func(sensorType string, equipmentID *int) (models.Threshold, bool)
+
+// GetSensorStats возвращает статистику показаний ?type= за ?from=&to=
+// (по умолчанию последние сутки): среднее, медиану, σ, процентили
+// ?percentiles= (через запятую, 0…100), минимум и максимум с моментом
+// достижения и долю времени в пределах действующих границ.
+// ?equipment_id= сужает выборку до оборудования.
+func GetSensorStats(db *sqlx.DB, limits EffectiveLimitFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		query := r.URL.Query()
+		stats := models.SensorStats{Type: query.Get("type"), Percentiles: []models.SensorPercentile{}}
+		if err := validation.SensorType("type", stats.Type); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		from, to, err := parseTimeRange(r, defaultSensorDataSpan)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, validation.CodeInvalidRange, "from", err.Error())
+			return
+		}
+		stats.From, stats.To = from, to
+		if stats.EquipmentID, err = parseEquipmentID(query.Get("equipment_id")); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		percentiles, err := parsePercentiles(query.Get("percentiles"))
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		limit, hasLimit := limits(stats.Type, stats.EquipmentID)
+		if err := loadSensorStats(db, &stats, percentiles, limit, hasLimit); err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		jsonResponse(w, stats)
+	}
+}
+
+func loadSensorStats(db *sqlx.DB, s *models.SensorStats, percentiles []float64, limit models.Threshold, hasLimit bool) error {
+	fractions := make([]float64, len(percentiles))
+	for i, p := range percentiles {
+		fractions[i] = p / 100
+	}
+
+	var row struct {
+		Samples        int             `db:"samples"`
+		Mean           *float64        `db:"mean"`
+		Median         *float64        `db:"median"`
+		StdDev         *float64        `db:"stddev"`
+		Percentiles    pq.Float64Array `db:"percentiles"`
+		MinValue       *float64        `db:"min_value"`
+		MinAt          sql.NullTime    `db:"min_at"`
+		MaxValue       *float64        `db:"max_value"`
+		MaxAt          sql.NullTime    `db:"max_at"`
+		Covered        *float64        `db:"covered"`
+		CoveredInRange *float64        `db:"covered_in_range"`
+		SamplesInRange int             `db:"samples_in_range"`
+	}
+	// Длительность показания - до следующего показания в окне
+	if err := db.Get(&row, `
+		WITH s AS (
+			SELECT value, timestamp,
+			       EXTRACT(EPOCH FROM LEAD(timestamp) OVER (ORDER BY timestamp, id) - timestamp) AS duration
+			FROM sensor_data
+			WHERE type = $1 AND timestamp >= $2 AND timestamp < $3
+			  AND ($4::int IS NULL OR id_equipment = $4)
+		)
+		SELECT COUNT(*) AS samples, AVG(value) AS mean, STDDEV_SAMP(value) AS stddev,
+		       PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY value) AS median,
+		       PERCENTILE_CONT($5::float8[]) WITHIN GROUP (ORDER BY value) AS percentiles,
+		       MIN(value) AS min_value, (ARRAY_AGG(timestamp ORDER BY value, timestamp))[1] AS min_at,
+		       MAX(value) AS max_value, (ARRAY_AGG(timestamp ORDER BY value DESC, timestamp))[1] AS max_at,
+		       SUM(duration) AS covered,
+		       SUM(duration) FILTER (WHERE value BETWEEN $6 AND $7) AS covered_in_range,
+		       COUNT(*) FILTER (WHERE value BETWEEN $6 AND $7) AS samples_in_range
+		FROM s`,
+		s.Type, s.From, s.To, s.EquipmentID, pq.Array(fractions), limit.MinValue, limit.MaxValue); err != nil {
+		return err
+	}
+
+	s.Samples = row.Samples
+	if s.Samples == 0 {
+		return nil
+	}
+	s.Mean, s.Median, s.StdDev = row.Mean, row.Median, row.StdDev
+	s.Min = &models.SensorExtreme{Value: *row.MinValue, Timestamp: row.MinAt.Time}
+	s.Max = &models.SensorExtreme{Value: *row.MaxValue, Timestamp: row.MaxAt.Time}
+	for i, v := range row.Percentiles {
+		s.Percentiles = append(s.Percentiles, models.SensorPercentile{P: percentiles[i], Value: v})
+	}
+
+	if hasLimit {
+		in := &models.TimeInRange{
+			MinValue:       limit.MinValue,
+			MaxValue:       limit.MaxValue,
+			SamplesPercent: 100 * float64(row.SamplesInRange) / float64(row.Samples),
+		}
+		// По единственному показанию длительность не определить
+		in.Percent = in.SamplesPercent
+		if covered := valueOrZero(row.Covered); covered > 0 {
+			in.Percent = 100 * valueOrZero(row.CoveredInRange) / covered
+		}
+		s.InRange = in
+	}
+	return nil
+}
+
+// parsePercentiles разбирает ?percentiles= - процентили 0…100 через запятую
+func parsePercentiles(value string) ([]float64, error) {
+	if value == "" {
+		return defaultStatsPercentiles, nil
+	}
+	var percentiles []float64
+	for _, p := range strings.Split(value, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || v < 0 || v > 100 {
+			return nil, validation.New(validation.CodeOutOfRange, "percentiles",
+				fmt.Sprintf("percentile %q must be a number between 0 and 100", p))
+		}
+		percentiles = append(percentiles, v)
+	}
+	return percentiles, nil
+}
@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var statsColumns = []string{"samples", "mean", "stddev", "median", "percentiles", "min_value", "min_at",
+	"max_value", "max_at", "covered", "covered_in_range", "samples_in_range"}
+
+func TestGetSensorStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	mock.ExpectQuery("PERCENTILE_CONT").
+		WithArgs("temperature", from, to, nil, sqlmock.AnyArg(), 20.0, 35.0).
+		WillReturnRows(sqlmock.NewRows(statsColumns).
+			AddRow(3600, 27.4, 2.1, 27.0, "{22.5,31.8}", 19.2, from.Add(5*time.Minute),
+				36.1, from.Add(40*time.Minute), 3599.0, 3239.1, 3230))
+
+	limits := func(sensorType string, equipmentID *int) (models.Threshold, bool) {
+		return models.Threshold{Type: sensorType, MinValue: 20, MaxValue: 35}, true
+	}
+	req := httptest.NewRequest("GET", "/api/sensor-data/stats?type=temperature&percentiles=5,95&from=2024-03-01T00:00:00Z&to=2024-03-01T01:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	api.GetSensorStats(sqlx.NewDb(db, "sqlmock"), limits)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stats models.SensorStats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, 3600, stats.Samples)
+	assert.Equal(t, 27.0, *stats.Median)
+	assert.Equal(t, []models.SensorPercentile{{P: 5, Value: 22.5}, {P: 95, Value: 31.8}}, stats.Percentiles)
+	assert.Equal(t, from.Add(40*time.Minute), stats.Max.Timestamp)
+	if assert.NotNil(t, stats.InRange) {
+		assert.InDelta(t, 90.0, stats.InRange.Percent, 0.01)
+		assert.InDelta(t, 89.72, stats.InRange.SamplesPercent, 0.01)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSensorStatsEmptyWindow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("PERCENTILE_CONT").
+		WillReturnRows(sqlmock.NewRows(statsColumns).
+			AddRow(0, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, 0))
+
+	limits := func(string, *int) (models.Threshold, bool) { return models.Threshold{}, false }
+	req := httptest.NewRequest("GET", "/api/sensor-data/stats?type=humidity", nil)
+	w := httptest.NewRecorder()
+
+	api.GetSensorStats(sqlx.NewDb(db, "sqlmock"), limits)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"samples":0`)
+	assert.Contains(t, w.Body.String(), `"mean":null`)
+	assert.Contains(t, w.Body.String(), `"in_range":null`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
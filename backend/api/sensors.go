@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/validation"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Наибольший ожидаемый период показаний датчика, секунд
+const maxExpectedInterval = 24 * 60 * 60
+
+// GetSensorStatus возвращает время последнего показания и состояние связи по каждому датчику
+func GetSensorStatus(status func() []models.SensorStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			jsonResponse(w, status())
+		default:
+			writeMethodNotAllowed(w)
+		}
+	}
+}
+
+// SensorIntervals: GET - ожидаемые периоды показаний датчиков, POST - задание
+// периода для датчика, DELETE ?type= - возврат к периоду по умолчанию.
+// Изменения применяются при следующей проверке связи.
+func SensorIntervals(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, POST, DELETE, OPTIONS")
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			intervals := []models.SensorInterval{}
+			if err := db.Select(&intervals, "SELECT * FROM sensor_intervals ORDER BY type"); err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			jsonResponse(w, intervals)
+		case http.MethodPost:
+			var interval models.SensorInterval
+			if err := decodeBody(r, &interval); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			if err := validation.First(
+				validation.SensorType("type", interval.Type),
+				validation.Between("expected_seconds", interval.ExpectedSeconds, 1, maxExpectedInterval),
+			); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+
+			var saved models.SensorInterval
+			if err := db.Get(&saved, `
+				INSERT INTO sensor_intervals (type, expected_seconds)
+				VALUES ($1, $2)
+				ON CONFLICT (type) DO UPDATE SET expected_seconds = EXCLUDED.expected_seconds
+				RETURNING *`,
+				interval.Type, interval.ExpectedSeconds); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to save sensor interval")
+				return
+			}
+			jsonResponse(w, saved)
+		case http.MethodDelete:
+			sensorType := r.URL.Query().Get("type")
+			if sensorType == "" {
+				writeValidationError(w, validation.Required("type"))
+				return
+			}
+			res, err := db.Exec("DELETE FROM sensor_intervals WHERE type = $1", sensorType)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to delete sensor interval")
+				return
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				writeError(w, http.StatusNotFound, codeNotFound, "type", "Sensor interval not found")
+				return
+			}
+			jsonResponse(w, map[string]string{"status": "success"})
+		default:
+			writeMethodNotAllowed(w)
+		}
+	}
+}
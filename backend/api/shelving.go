@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+	"realtime-app/models"
+	"realtime-app/validation"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Максимальный срок откладывания тревоги
+const maxShelveMinutes = 24 * 60
+
+// ShelvingChangedCallback вызывается после изменения списка отложенных тревог
+type ShelvingChangedCallback func()
+
+// GetAlarmShelves возвращает действующие откладывания; ?all=true - включая истекшие и снятые
+func GetAlarmShelves(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		shelves := []models.AlarmShelf{}
+		query := `SELECT * FROM alarm_shelves
+                  WHERE unshelved_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+                  ORDER BY shelved_at DESC`
+		if r.URL.Query().Get("all") == "true" {
+			query = "SELECT * FROM alarm_shelves ORDER BY shelved_at DESC LIMIT 200"
+		}
+		if err := db.Select(&shelves, query); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, shelves)
+	}
+}
+
+// ShelveAlarm откладывает тревоги по датчику на заданное время с указанием причины
+func ShelveAlarm(db *sqlx.DB, callback ShelvingChangedCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		var req struct {
+			Type            string `json:"type"`
+			Reason          string `json:"reason"`
+			User            string `json:"user"`
+			DurationMinutes int    `json:"duration_minutes"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if err := validation.First(
+			validation.SensorType("type", req.Type),
+			validation.NotEmpty("reason", req.Reason),
+			validation.NotEmpty("user", req.User),
+			validation.Between("duration_minutes", req.DurationMinutes, 1, maxShelveMinutes),
+		); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		var shelf models.AlarmShelf
+		if err := db.Get(&shelf, `
+			INSERT INTO alarm_shelves (type, reason, shelved_by, expires_at)
+			VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(mins => $4))
+			RETURNING *`,
+			req.Type, req.Reason, req.User, req.DurationMinutes); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to shelve alarm")
+			return
+		}
+
+		callback()
+		jsonResponse(w, shelf)
+	}
+}
+
+// UnshelveAlarm досрочно снимает откладывание по его id
+func UnshelveAlarm(db *sqlx.DB, callback ShelvingChangedCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		var req struct {
+			ID   int    `json:"id"`
+			User string `json:"user"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if err := validation.First(
+			validation.Positive("id", req.ID),
+			validation.NotEmpty("user", req.User),
+		); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+
+		res, err := db.Exec(`
+			UPDATE alarm_shelves SET unshelved_at = CURRENT_TIMESTAMP, unshelved_by = $2
+			WHERE id = $1 AND unshelved_at IS NULL`,
+			req.ID, req.User)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to unshelve alarm")
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			writeError(w, http.StatusNotFound, codeNotFound, "id", "Shelve not found")
+			return
+		}
+
+		callback()
+		jsonResponse(w, map[string]string{"status": "success"})
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+	database "realtime-app/db"
+	"realtime-app/models"
+	"realtime-app/validation"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ThresholdTemplates: GET - шаблоны границ по типам оборудования,
+// POST - создание или изменение шаблона, DELETE ?id= - удаление.
+// Изменение шаблона применяется к оборудованию через /api/templates/apply;
+// после удаления шаблона созданные из него границы остаются как заданные вручную.
+func ThresholdTemplates(db *sqlx.DB, callback LimitsChangedCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, POST, DELETE, OPTIONS")
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			templates := []models.ThresholdTemplate{}
+			if err := db.Select(&templates, "SELECT * FROM threshold_templates ORDER BY equipment_type, sensor_type"); err != nil {
+				writeInternalError(w, err)
+				return
+			}
+			jsonResponse(w, templates)
+		case http.MethodPost:
+			var tmpl models.ThresholdTemplate
+			if err := decodeBody(r, &tmpl); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+			if err := validation.First(
+				validation.NotEmpty("equipment_type", tmpl.EquipmentType),
+				validation.SensorType("sensor_type", tmpl.SensorType),
+				validation.Range(tmpl.MinValue, tmpl.MaxValue),
+			); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+
+			var saved models.ThresholdTemplate
+			if err := db.Get(&saved, `
+				INSERT INTO threshold_templates (equipment_type, sensor_type, min_value, max_value)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (equipment_type, sensor_type) DO UPDATE SET
+					min_value = EXCLUDED.min_value,
+					max_value = EXCLUDED.max_value
+				RETURNING *`,
+				tmpl.EquipmentType, tmpl.SensorType, tmpl.MinValue, tmpl.MaxValue); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to save template")
+				return
+			}
+			jsonResponse(w, saved)
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				writeValidationError(w, validation.Required("id"))
+				return
+			}
+			res, err := db.Exec("DELETE FROM threshold_templates WHERE id = $1", id)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to delete template")
+				return
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				writeError(w, http.StatusNotFound, codeNotFound, "id", "Template not found")
+				return
+			}
+			// Источник границ меняется с template на equipment
+			callback()
+			jsonResponse(w, map[string]string{"status": "success"})
+		default:
+			writeMethodNotAllowed(w)
+		}
+	}
+}
+
+// ApplyThresholdTemplates повторно применяет шаблоны ко всему оборудованию
+// или только к equipment_type / equipment_id. Заданные вручную границы сохраняются.
+func ApplyThresholdTemplates(db *sqlx.DB, callback LimitsChangedCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, OPTIONS")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		var req struct {
+			EquipmentType *string `json:"equipment_type"`
+			EquipmentID   *int    `json:"equipment_id"`
+		}
+		if r.ContentLength != 0 {
+			if err := decodeBody(r, &req); err != nil {
+				writeValidationError(w, err)
+				return
+			}
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+		defer tx.Rollback()
+
+		result, err := database.ApplyTemplates(tx, req.EquipmentType, req.EquipmentID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to apply templates: "+err.Error())
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			writeInternalError(w, err)
+			return
+		}
+
+		callback()
+		jsonResponse(w, result)
+	}
+}
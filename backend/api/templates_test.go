@@ -0,0 +1,68 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyThresholdTemplatesPreservesOverrides(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	for i := 0; i < 3; i++ {
+		mock.ExpectExec("INSERT INTO process_parameters").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) AS total").
+		WithArgs("Пресс", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"total", "preserved"}).AddRow(4, 1))
+	mock.ExpectQuery("INSERT INTO reference_parameters").
+		WithArgs("Пресс", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(true).AddRow(false).AddRow(false))
+	mock.ExpectCommit()
+
+	reloaded := false
+	body, _ := json.Marshal(map[string]string{"equipment_type": "Пресс"})
+	req := httptest.NewRequest("POST", "/api/templates/apply", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.ApplyThresholdTemplates(sqlx.NewDb(db, "sqlmock"), func() { reloaded = true })(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result models.TemplateApplyResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, models.TemplateApplyResult{Total: 4, Created: 1, Updated: 2, Preserved: 1}, result)
+	assert.True(t, reloaded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateEquipmentValidation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	req := httptest.NewRequest("POST", "/api/equipment/create", bytes.NewBufferString(`{"name": "Пресс 2", "type": "Пресс", "status": "Сломано"}`))
+	w := httptest.NewRecorder()
+
+	api.CreateEquipment(sqlx.NewDb(db, "sqlmock"), func() {})(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"status"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"realtime-app/models"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Окно анализа по умолчанию
+const defaultSuggestSpan = 7 * 24 * time.Hour
+
+// Показаний за окно недостаточно для расчета
+var errNotEnoughData = errors.New("not enough data")
+
+// Минимальное число показаний, по которому имеет смысл предлагать пороги
+const minSuggestSamples = 30
+
+// GetThresholdSuggestion предлагает границы для ?type= по показаниям за
+// ?from=&to=: method=percentile (lower, upper - доли, по умолчанию 0.005 и 0.995)
+// или method=sigma (среднее ± k·σ, k по умолчанию 3). ?equipment_id= сужает
+// выборку до оборудования. В ответе - ожидаемая частота тревог для предложенных
+// и для действующих границ limits.
+func GetThresholdSuggestion(db *sqlx.DB, limits EffectiveLimitFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		query := r.URL.Query()
+		sensorType := query.Get("type")
+		if _, err := models.ParseSensorType(sensorType); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		from, to, err := parseTimeRange(r, defaultSuggestSpan)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		suggestion := models.ThresholdSuggestion{Type: sensorType, From: from, To: to, Method: query.Get("method")}
+		if suggestion.Method == "" {
+			suggestion.Method = models.SuggestPercentile
+		}
+		if v := query.Get("equipment_id"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "invalid equipment_id", http.StatusBadRequest)
+				return
+			}
+			suggestion.EquipmentID = &id
+		}
+
+		lower, errLower := floatParam(query.Get("lower"), 0.005)
+		upper, errUpper := floatParam(query.Get("upper"), 0.995)
+		k, errK := floatParam(query.Get("k"), 3)
+		for _, err := range []error{errLower, errUpper, errK} {
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch suggestion.Method {
+		case models.SuggestPercentile:
+			if !(lower >= 0 && lower < upper && upper <= 1) {
+				http.Error(w, "lower and upper must satisfy 0 <= lower < upper <= 1", http.StatusBadRequest)
+				return
+			}
+			err = suggestPercentile(db, &suggestion, lower, upper)
+		case models.SuggestSigma:
+			if k <= 0 {
+				http.Error(w, "k must be positive", http.StatusBadRequest)
+				return
+			}
+			err = suggestSigma(db, &suggestion, k)
+		default:
+			http.Error(w, fmt.Sprintf("unknown method %q", suggestion.Method), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, errNotEnoughData) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		current, hasCurrent := limits(suggestion.Type, suggestion.EquipmentID)
+		if err := loadSuggestionRates(db, &suggestion, current, hasCurrent); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, suggestion)
+	}
+}
+
+// Отбор показаний датчика за окно; $4 - оборудование или NULL
+const suggestSamples = `
+	FROM sensor_data
+	WHERE type = $1 AND timestamp >= $2 AND timestamp < $3
+	  AND ($4::int IS NULL OR id_equipment = $4)`
+
+func suggestPercentile(db *sqlx.DB, s *models.ThresholdSuggestion, lower, upper float64) error {
+	var row struct {
+		Samples int      `db:"samples"`
+		Mean    *float64 `db:"mean"`
+		StdDev  *float64 `db:"stddev"`
+		Lower   *float64 `db:"lower"`
+		Upper   *float64 `db:"upper"`
+	}
+	if err := db.Get(&row, `
+		SELECT COUNT(*) AS samples, AVG(value) AS mean, STDDEV_SAMP(value) AS stddev,
+		       PERCENTILE_CONT($5) WITHIN GROUP (ORDER BY value) AS lower,
+		       PERCENTILE_CONT($6) WITHIN GROUP (ORDER BY value) AS upper
+		`+suggestSamples,
+		s.Type, s.From, s.To, s.EquipmentID, lower, upper); err != nil {
+		return err
+	}
+	if err := checkSamples(row.Samples); err != nil {
+		return err
+	}
+	s.Samples, s.Mean, s.StdDev = row.Samples, *row.Mean, valueOrZero(row.StdDev)
+	s.MinValue, s.MaxValue = roundLimit(*row.Lower), roundLimit(*row.Upper)
+	return checkSpread(s)
+}
+
+func suggestSigma(db *sqlx.DB, s *models.ThresholdSuggestion, k float64) error {
+	var row struct {
+		Samples int      `db:"samples"`
+		Mean    *float64 `db:"mean"`
+		StdDev  *float64 `db:"stddev"`
+	}
+	if err := db.Get(&row, `
+		SELECT COUNT(*) AS samples, AVG(value) AS mean, STDDEV_SAMP(value) AS stddev
+		`+suggestSamples,
+		s.Type, s.From, s.To, s.EquipmentID); err != nil {
+		return err
+	}
+	if err := checkSamples(row.Samples); err != nil {
+		return err
+	}
+	s.Samples, s.Mean, s.StdDev = row.Samples, *row.Mean, valueOrZero(row.StdDev)
+	s.MinValue, s.MaxValue = roundLimit(s.Mean-k*s.StdDev), roundLimit(s.Mean+k*s.StdDev)
+	return checkSpread(s)
+}
+
+// loadSuggestionRates оценивает частоту тревог для предложенных и текущих порогов
+func loadSuggestionRates(db *sqlx.DB, s *models.ThresholdSuggestion, current models.Threshold, hasCurrent bool) error {
+	expected, err := alarmRate(db, s, s.MinValue, s.MaxValue)
+	if err != nil {
+		return err
+	}
+	s.Expected = expected
+
+	if hasCurrent {
+		rate, err := alarmRate(db, s, current.MinValue, current.MaxValue)
+		if err != nil {
+			return err
+		}
+		s.Current = &rate
+	}
+	return nil
+}
+
+func alarmRate(db *sqlx.DB, s *models.ThresholdSuggestion, minValue, maxValue float64) (models.AlarmRate, error) {
+	rate := models.AlarmRate{MinValue: minValue, MaxValue: maxValue}
+	if err := db.Get(&rate, `
+		WITH samples AS (
+			SELECT value < $5 OR value > $6 AS outside,
+			       LAG(value < $5 OR value > $6) OVER (ORDER BY timestamp) AS was_outside
+			`+suggestSamples+`
+		)
+		SELECT COUNT(*) FILTER (WHERE outside) AS outside_samples,
+		       COUNT(*) FILTER (WHERE outside AND NOT COALESCE(was_outside, FALSE)) AS alarms
+		FROM samples`,
+		s.Type, s.From, s.To, s.EquipmentID, minValue, maxValue); err != nil {
+		return rate, err
+	}
+	if s.Samples > 0 {
+		rate.OutsideRatio = float64(rate.OutsideSamples) / float64(s.Samples)
+	}
+	rate.AlarmsPerDay = float64(rate.Alarms) / s.To.Sub(s.From).Hours() * 24
+	return rate, nil
+}
+
+func checkSamples(samples int) error {
+	if samples < minSuggestSamples {
+		return fmt.Errorf("%w: %d samples in the window, at least %d required", errNotEnoughData, samples, minSuggestSamples)
+	}
+	return nil
+}
+
+func checkSpread(s *models.ThresholdSuggestion) error {
+	if s.MinValue >= s.MaxValue {
+		return fmt.Errorf("%w: readings have no spread in the window", errNotEnoughData)
+	}
+	return nil
+}
+
+func floatParam(value string, fallback float64) (float64, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return f, nil
+}
+
+func valueOrZero(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// roundLimit округляет границу до сотых, как их вводят в настройках порогов
+func roundLimit(v float64) float64 {
+	return math.Round(v*100) / 100
+}
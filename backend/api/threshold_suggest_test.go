@@ -0,0 +1,121 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/api"
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetThresholdSuggestionSigma(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(48 * time.Hour)
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) AS samples, AVG\\(value\\)").
+		WithArgs("temperature", from, to, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"samples", "mean", "stddev"}).
+			AddRow(1000, 27.5, 1.5))
+	mock.ExpectQuery("WITH samples AS").
+		WithArgs("temperature", from, to, nil, 23.0, 32.0).
+		WillReturnRows(sqlmock.NewRows([]string{"outside_samples", "alarms"}).AddRow(3, 2))
+	mock.ExpectQuery("WITH samples AS").
+		WithArgs("temperature", from, to, nil, 26.0, 29.0).
+		WillReturnRows(sqlmock.NewRows([]string{"outside_samples", "alarms"}).AddRow(400, 60))
+
+	req := httptest.NewRequest("GET", "/api/thresholds/suggest?type=temperature&method=sigma&from=2024-03-01T00:00:00Z&to=2024-03-03T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	api.GetThresholdSuggestion(sqlx.NewDb(db, "sqlmock"), func(sensorType string, equipmentID *int) (models.Threshold, bool) {
+		assert.Nil(t, equipmentID)
+		return models.Threshold{Type: sensorType, MinValue: 26, MaxValue: 29}, true
+	})(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var suggestion models.ThresholdSuggestion
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &suggestion))
+	assert.Equal(t, 23.0, suggestion.MinValue)
+	assert.Equal(t, 32.0, suggestion.MaxValue)
+	assert.Equal(t, 1.0, suggestion.Expected.AlarmsPerDay)
+	assert.InDelta(t, 0.003, suggestion.Expected.OutsideRatio, 1e-9)
+	if assert.NotNil(t, suggestion.Current) {
+		assert.Equal(t, 30.0, suggestion.Current.AlarmsPerDay)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetThresholdSuggestionNotEnoughData(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("PERCENTILE_CONT").
+		WillReturnRows(sqlmock.NewRows([]string{"samples", "mean", "stddev", "lower", "upper"}).
+			AddRow(5, 27.0, 1.0, 25.0, 29.0))
+
+	req := httptest.NewRequest("GET", "/api/thresholds/suggest?type=temperature", nil)
+	w := httptest.NewRecorder()
+
+	limits := func(string, *int) (models.Threshold, bool) { return models.Threshold{}, false }
+	api.GetThresholdSuggestion(sqlx.NewDb(db, "sqlmock"), limits)(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Для оборудования текущая частота тревог считается по его собственным границам
+func TestGetThresholdSuggestionEquipmentLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) AS samples, AVG\\(value\\)").
+		WithArgs("temperature", from, to, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"samples", "mean", "stddev"}).
+			AddRow(1000, 27.5, 1.5))
+	mock.ExpectQuery("WITH samples AS").
+		WithArgs("temperature", from, to, 2, 23.0, 32.0).
+		WillReturnRows(sqlmock.NewRows([]string{"outside_samples", "alarms"}).AddRow(3, 2))
+	mock.ExpectQuery("WITH samples AS").
+		WithArgs("temperature", from, to, 2, 24.0, 31.0).
+		WillReturnRows(sqlmock.NewRows([]string{"outside_samples", "alarms"}).AddRow(10, 4))
+
+	req := httptest.NewRequest("GET", "/api/thresholds/suggest?type=temperature&method=sigma&equipment_id=2&from=2024-03-01T00:00:00Z&to=2024-03-02T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	api.GetThresholdSuggestion(sqlx.NewDb(db, "sqlmock"), func(sensorType string, equipmentID *int) (models.Threshold, bool) {
+		if equipmentID != nil && *equipmentID == 2 {
+			return models.Threshold{Type: sensorType, MinValue: 24, MaxValue: 31}, true
+		}
+		return models.Threshold{Type: sensorType, MinValue: 20, MaxValue: 35}, true
+	})(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var suggestion models.ThresholdSuggestion
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &suggestion))
+	if assert.NotNil(t, suggestion.Current) {
+		assert.Equal(t, 31.0, suggestion.Current.MaxValue)
+		assert.Equal(t, 4.0, suggestion.Current.AlarmsPerDay)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
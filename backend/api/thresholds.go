@@ -1,10 +1,17 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	database "realtime-app/db"
 	"realtime-app/models"
+	"realtime-app/validation"
+	"strconv"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -30,11 +37,24 @@ func GetThresholds(db *sqlx.DB) http.HandlerFunc {
 
 type UpdateThresholdCallback func(threshold models.Threshold)
 
+// Автор изменения, если клиент его не указал
+const unknownUser = "unknown"
+
+// Коды ошибок оптимистичной блокировки
+const (
+	codePreconditionRequired = "precondition_required"
+	codeVersionConflict      = "version_conflict"
+)
+
+// UpdateThresholdWrapper изменяет порог. Заголовок If-Match с версией порога
+// (ETag из ответа или поле version из GetThresholds) обязателен: если порог
+// успели изменить, возвращается 409 с текущим значением.
 func UpdateThresholdWrapper(db *sqlx.DB, callback UpdateThresholdCallback) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
 		if r.Method == "OPTIONS" {
@@ -43,26 +63,62 @@ func UpdateThresholdWrapper(db *sqlx.DB, callback UpdateThresholdCallback) http.
 		}
 
 		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w)
 			return
 		}
 
-		var threshold models.Threshold
-		if err := json.NewDecoder(r.Body).Decode(&threshold); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		ifVersion, err := parseIfMatch(r.Header.Get("If-Match"))
+		if err != nil {
+			writeError(w, http.StatusPreconditionRequired, codePreconditionRequired, "If-Match", err.Error())
 			return
 		}
 
-		// Обновление в БД
-		if _, err := db.Exec(`
-            INSERT INTO thresholds (type, min_value, max_value)
-            VALUES ($1, $2, $3)
-            ON CONFLICT (type) DO UPDATE SET 
-                min_value = EXCLUDED.min_value, 
-                max_value = EXCLUDED.max_value,
-                updated_at = CURRENT_TIMESTAMP`,
-			threshold.Type, threshold.MinValue, threshold.MaxValue); err != nil {
-			http.Error(w, "Failed to update threshold", http.StatusInternalServerError)
+		// Границы - указатели, чтобы отличить пропущенное поле от нуля
+		var req struct {
+			Type     string   `json:"type"`
+			MinValue *float64 `json:"min_value"`
+			MaxValue *float64 `json:"max_value"`
+			User     string   `json:"user"`
+			Reason   string   `json:"reason"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if err := validation.SensorType("type", req.Type); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if req.MinValue == nil {
+			writeValidationError(w, validation.Required("min_value"))
+			return
+		}
+		if req.MaxValue == nil {
+			writeValidationError(w, validation.Required("max_value"))
+			return
+		}
+		update := models.Threshold{Type: req.Type, MinValue: *req.MinValue, MaxValue: *req.MaxValue}
+		if err := validation.Threshold(update); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if req.User == "" {
+			req.User = unknownUser
+		}
+
+		// Обновление в БД с записью в историю изменений
+		threshold, err := database.SaveThreshold(db, update, models.ThresholdChange{
+			User:      req.User,
+			Reason:    req.Reason,
+			IfVersion: ifVersion,
+		})
+		var conflict *database.VersionConflictError
+		if errors.As(err, &conflict) {
+			writeVersionConflict(w, conflict)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to update threshold")
 			return
 		}
 
@@ -71,7 +127,149 @@ func UpdateThresholdWrapper(db *sqlx.DB, callback UpdateThresholdCallback) http.
 
 		// Явно устанавливаем Content-Type перед отправкой ответа
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", thresholdETag(threshold.Version))
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 	}
 }
+
+// thresholdETag представляет версию порога в виде ETag
+func thresholdETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseIfMatch разбирает If-Match: "3", W/"3" или 3. Значение * отключает
+// проверку версии (nil), отсутствие заголовка - ошибка.
+func parseIfMatch(value string) (*int, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "":
+		return nil, fmt.Errorf("If-Match header with the threshold version is required")
+	case "*":
+		return nil, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(value, "W/"), `"`))
+	if err != nil {
+		return nil, fmt.Errorf("If-Match must contain the threshold version, got %s", value)
+	}
+	return &version, nil
+}
+
+// writeVersionConflict отвечает 409 с текущим значением порога
+func writeVersionConflict(w http.ResponseWriter, conflict *database.VersionConflictError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", thresholdETag(conflict.Current.Version))
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(struct {
+		*validation.Error
+		Current models.Threshold `json:"current"`
+	}{
+		Error:   validation.New(codeVersionConflict, "version", conflict.Error()),
+		Current: conflict.Current,
+	})
+}
+
+// GetThresholdHistory возвращает историю изменений порога ?type=, новые записи первыми
+func GetThresholdHistory(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "GET, OPTIONS")
+
+		sensorType := r.URL.Query().Get("type")
+		if sensorType == "" {
+			http.Error(w, "type is required", http.StatusBadRequest)
+			return
+		}
+
+		history := []models.ThresholdHistory{}
+		if err := db.Select(&history, `
+			SELECT * FROM threshold_history
+			WHERE type = $1
+			ORDER BY changed_at DESC, id DESC`,
+			sensorType); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jsonResponse(w, history)
+	}
+}
+
+// RollbackThreshold восстанавливает значения порога, установленные записью истории history_id.
+// Откат сам записывается в историю со ссылкой на восстановленную запись.
+// If-Match с текущей версией порога обязателен, как и в UpdateThresholdWrapper.
+func RollbackThreshold(db *sqlx.DB, callback UpdateThresholdCallback) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w)
+			return
+		}
+
+		ifVersion, err := parseIfMatch(r.Header.Get("If-Match"))
+		if err != nil {
+			writeError(w, http.StatusPreconditionRequired, codePreconditionRequired, "If-Match", err.Error())
+			return
+		}
+
+		var req struct {
+			HistoryID int    `json:"history_id"`
+			User      string `json:"user"`
+			Reason    string `json:"reason"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		if req.HistoryID == 0 {
+			writeValidationError(w, validation.Required("history_id"))
+			return
+		}
+		if req.User == "" {
+			req.User = unknownUser
+		}
+
+		var version models.ThresholdHistory
+		err = db.Get(&version, "SELECT * FROM threshold_history WHERE id = $1", req.HistoryID)
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, codeNotFound, "history_id", "History entry not found")
+			return
+		}
+		if err != nil {
+			writeInternalError(w, err)
+			return
+		}
+
+		if req.Reason == "" {
+			req.Reason = fmt.Sprintf("rollback to history entry #%d", version.ID)
+		}
+		threshold, err := database.SaveThreshold(db, models.Threshold{
+			Type:     version.Type,
+			MinValue: version.NewMinValue,
+			MaxValue: version.NewMaxValue,
+		}, models.ThresholdChange{
+			User:       req.User,
+			Reason:     req.Reason,
+			RollbackOf: &version.ID,
+			IfVersion:  ifVersion,
+		})
+		var conflict *database.VersionConflictError
+		if errors.As(err, &conflict) {
+			writeVersionConflict(w, conflict)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "", "Failed to roll back threshold")
+			return
+		}
+
+		callback(threshold)
+		w.Header().Set("ETag", thresholdETag(threshold.Version))
+		jsonResponse(w, threshold)
+	}
+}
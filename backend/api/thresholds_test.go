@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"realtime-app/api"
 	"realtime-app/models"
+	"realtime-app/validation"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -69,16 +70,27 @@ func TestUpdateThresholdWrapper(t *testing.T) {
 		Type:     "temperature",
 		MinValue: 25,
 		MaxValue: 40,
+		Version:  4,
 	}
 
-	// Настройка ожидаемого запроса
-	mock.ExpectExec("INSERT INTO thresholds (.+) VALUES (.+)").
+	// Настройка ожидаемых запросов: порог и запись в историю в одной транзакции
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT min_value, max_value, version FROM thresholds WHERE type = (.+) FOR UPDATE").
+		WithArgs(testThreshold.Type).
+		WillReturnRows(sqlmock.NewRows([]string{"min_value", "max_value", "version"}).AddRow(20, 35, 3))
+	mock.ExpectQuery("INSERT INTO thresholds (.+) VALUES (.+)").
 		WithArgs(testThreshold.Type, testThreshold.MinValue, testThreshold.MaxValue).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "min_value", "max_value", "version"}).
+			AddRow(testThreshold.Type, testThreshold.MinValue, testThreshold.MaxValue, testThreshold.Version))
+	mock.ExpectExec("INSERT INTO threshold_history").
+		WithArgs(testThreshold.Type, 20.0, 35.0, testThreshold.MinValue, testThreshold.MaxValue, "unknown", "", nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	// Создание тестового запроса
 	body, _ := json.Marshal(testThreshold)
 	req := httptest.NewRequest("POST", "/api/thresholds/update", bytes.NewReader(body))
+	req.Header.Set("If-Match", `"3"`)
 	w := httptest.NewRecorder()
 
 	// Mock для callback
@@ -95,6 +107,7 @@ func TestUpdateThresholdWrapper(t *testing.T) {
 	// Проверки
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
 
 	var result map[string]string
 	err = json.Unmarshal(w.Body.Bytes(), &result)
@@ -105,3 +118,176 @@ func TestUpdateThresholdWrapper(t *testing.T) {
 	// Проверяем, что все ожидания по mock выполнены
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestUpdateThresholdWrapperValidation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	cases := []struct {
+		body  string
+		code  string
+		field string
+	}{
+		{``, validation.CodeInvalidBody, ""},
+		{`{"min_value": 1, "max_value": 2}`, validation.CodeRequired, "type"},
+		{`{"type": "voltage", "min_value": 1, "max_value": 2}`, validation.CodeUnknownType, "type"},
+		{`{"type": "temperature", "max_value": 2}`, validation.CodeRequired, "min_value"},
+		{`{"type": "temperature", "min_value": "low", "max_value": 2}`, validation.CodeInvalidValue, "min_value"},
+		{`{"type": "temperature", "min_value": 40, "max_value": 30}`, validation.CodeInvalidRange, "min_value"},
+	}
+
+	handler := api.UpdateThresholdWrapper(sqlx.NewDb(db, "sqlmock"), func(models.Threshold) {
+		t.Error("Callback should not be called")
+	})
+	for _, c := range cases {
+		req := httptest.NewRequest("POST", "/api/thresholds/update", bytes.NewBufferString(c.body))
+		req.Header.Set("If-Match", `"1"`)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, c.body)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var apiErr validation.Error
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+		assert.Equal(t, c.code, apiErr.Code, c.body)
+		assert.Equal(t, c.field, apiErr.Field, c.body)
+		assert.NotEmpty(t, apiErr.Message)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateThresholdWrapperVersionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	handler := api.UpdateThresholdWrapper(sqlx.NewDb(db, "sqlmock"), func(models.Threshold) {
+		t.Error("Callback should not be called")
+	})
+	body := `{"type": "temperature", "min_value": 25, "max_value": 40}`
+
+	// Без If-Match изменение не принимается
+	req := httptest.NewRequest("POST", "/api/thresholds/update", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	// Порог успел изменить другой пользователь
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT min_value, max_value, version FROM thresholds WHERE type = (.+) FOR UPDATE").
+		WithArgs("temperature").
+		WillReturnRows(sqlmock.NewRows([]string{"min_value", "max_value", "version"}).AddRow(22, 33, 6))
+	mock.ExpectQuery("SELECT \\* FROM thresholds WHERE type").
+		WithArgs("temperature").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "min_value", "max_value", "version"}).
+			AddRow(1, "temperature", 22, 33, 6))
+	mock.ExpectRollback()
+
+	req = httptest.NewRequest("POST", "/api/thresholds/update", bytes.NewBufferString(body))
+	req.Header.Set("If-Match", `"5"`)
+	w = httptest.NewRecorder()
+	handler(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, `"6"`, w.Header().Get("ETag"))
+
+	var result struct {
+		Code    string           `json:"code"`
+		Current models.Threshold `json:"current"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "version_conflict", result.Code)
+	assert.Equal(t, 33.0, result.Current.MaxValue)
+	assert.Equal(t, 6, result.Current.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRollbackThreshold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery("SELECT \\* FROM threshold_history WHERE id").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "new_min_value", "new_max_value", "changed_by"}).
+			AddRow(12, "humidity", 30, 80, "Иванов"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT min_value, max_value, version FROM thresholds").
+		WithArgs("humidity").
+		WillReturnRows(sqlmock.NewRows([]string{"min_value", "max_value", "version"}).AddRow(10, 95, 2))
+	mock.ExpectQuery("INSERT INTO thresholds").
+		WithArgs("humidity", 30.0, 80.0).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "min_value", "max_value"}).AddRow("humidity", 30, 80))
+	mock.ExpectExec("INSERT INTO threshold_history").
+		WithArgs("humidity", 10.0, 95.0, 30.0, 80.0, "Петров", "rollback to history entry #12", 12).
+		WillReturnResult(sqlmock.NewResult(13, 1))
+	mock.ExpectCommit()
+
+	var restored models.Threshold
+	callback := func(threshold models.Threshold) { restored = threshold }
+
+	body, _ := json.Marshal(map[string]interface{}{"history_id": 12, "user": "Петров"})
+	req := httptest.NewRequest("POST", "/api/thresholds/rollback", bytes.NewReader(body))
+	req.Header.Set("If-Match", `"2"`)
+	w := httptest.NewRecorder()
+
+	api.RollbackThreshold(sqlxDB, callback)(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.Threshold{Type: "humidity", MinValue: 30, MaxValue: 80}, restored)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRollbackThresholdVersionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	handler := api.RollbackThreshold(sqlx.NewDb(db, "sqlmock"), func(models.Threshold) {
+		t.Error("Callback should not be called")
+	})
+	body := `{"history_id": 12, "user": "Петров"}`
+
+	// Без If-Match откат не выполняется
+	req := httptest.NewRequest("POST", "/api/thresholds/rollback", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	// Порог изменили после того, как клиент прочитал версию 2
+	mock.ExpectQuery("SELECT \\* FROM threshold_history WHERE id").
+		WithArgs(12).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "new_min_value", "new_max_value", "changed_by"}).
+			AddRow(12, "humidity", 30, 80, "Иванов"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT min_value, max_value, version FROM thresholds").
+		WithArgs("humidity").
+		WillReturnRows(sqlmock.NewRows([]string{"min_value", "max_value", "version"}).AddRow(35, 70, 3))
+	mock.ExpectQuery("SELECT \\* FROM thresholds WHERE type").
+		WithArgs("humidity").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "min_value", "max_value", "version"}).
+			AddRow(2, "humidity", 35, 70, 3))
+	mock.ExpectRollback()
+
+	req = httptest.NewRequest("POST", "/api/thresholds/rollback", bytes.NewBufferString(body))
+	req.Header.Set("If-Match", `"2"`)
+	w = httptest.NewRecorder()
+	handler(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
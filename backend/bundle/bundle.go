@@ -0,0 +1,168 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"realtime-app/models"
+	"realtime-app/validation"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/yaml.v3"
+)
+
+// Version - текущая версия формата выгрузки
+const Version = 1
+
+// Форматы выгрузки
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// Bundle - переносимая выгрузка настроек порогов между установками.
+// Границы оборудования ссылаются на оборудование по имени, а на параметр -
+// по типу датчика или, для параметров без датчика, по имени, так как
+// идентификаторы в разных базах не совпадают.
+type Bundle struct {
+	Version    int              `json:"version" yaml:"version"`
+	ExportedAt time.Time        `json:"exported_at" yaml:"exported_at"`
+	Thresholds []ThresholdEntry `json:"thresholds" yaml:"thresholds"`
+	Limits     []LimitEntry     `json:"limits" yaml:"limits"`
+}
+
+// ThresholdEntry - общий порог по типу датчика
+type ThresholdEntry struct {
+	Type     string  `json:"type" yaml:"type" db:"type"`
+	MinValue float64 `json:"min_value" yaml:"min_value" db:"min_value"`
+	MaxValue float64 `json:"max_value" yaml:"max_value" db:"max_value"`
+}
+
+// LimitEntry - границы параметра оборудования (reference_parameters)
+type LimitEntry struct {
+	Equipment  string  `json:"equipment" yaml:"equipment" db:"equipment"`
+	Parameter  string  `json:"parameter" yaml:"parameter" db:"parameter"`
+	Units      string  `json:"units" yaml:"units" db:"units"`
+	SensorType *string `json:"sensor_type,omitempty" yaml:"sensor_type,omitempty" db:"sensor_type"`
+	MinValue   float64 `json:"min_value" yaml:"min_value" db:"min_value"`
+	MaxValue   float64 `json:"max_value" yaml:"max_value" db:"max_value"`
+}
+
+func (l LimitEntry) key() string {
+	return l.Equipment + "/" + l.Parameter
+}
+
+const limitsQuery = `
+	SELECT e.name AS equipment, pp.name AS parameter, pp.units, pp.sensor_type,
+	       rp.min_value, rp.max_value
+	FROM reference_parameters rp
+	JOIN process_parameters pp ON pp.id = rp.id_param
+	JOIN equipment e ON e.id = pp.id_equipment
+	ORDER BY e.name, pp.name`
+
+// Export собирает текущие пороги и границы оборудования
+func Export(db sqlx.Queryer) (Bundle, error) {
+	b := Bundle{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Thresholds: []ThresholdEntry{},
+		Limits:     []LimitEntry{},
+	}
+	if err := sqlx.Select(db, &b.Thresholds, "SELECT type, min_value, max_value FROM thresholds ORDER BY type"); err != nil {
+		return b, err
+	}
+	if err := sqlx.Select(db, &b.Limits, limitsQuery); err != nil {
+		return b, err
+	}
+	return b, nil
+}
+
+// ParseFormat нормализует имя формата; пустое значение означает YAML
+func ParseFormat(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "", FormatYAML, "yml":
+		return FormatYAML, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown bundle format %q", value)
+	}
+}
+
+// Encode записывает выгрузку в выбранном формате
+func Encode(w io.Writer, b Bundle, format string) error {
+	if format == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(b)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(b); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Decode читает выгрузку. JSON является подмножеством YAML, поэтому
+// формат важен только для точных сообщений об ошибках.
+func Decode(r io.Reader, format string) (Bundle, error) {
+	var b Bundle
+	var err error
+	if format == FormatJSON {
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&b)
+	} else {
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		err = dec.Decode(&b)
+	}
+	if err != nil {
+		return b, fmt.Errorf("invalid %s bundle: %v", format, err)
+	}
+	return b, nil
+}
+
+// Validate проверяет версию формата, границы и отсутствие дубликатов.
+// Возвращает все найденные ошибки, а не только первую.
+func Validate(b Bundle) []string {
+	var problems []string
+	if b.Version != Version {
+		problems = append(problems, fmt.Sprintf("unsupported bundle version %d (expected %d)", b.Version, Version))
+	}
+
+	seenTypes := make(map[string]bool)
+	for i, t := range b.Thresholds {
+		if err := validation.Threshold(models.Threshold{Type: t.Type, MinValue: t.MinValue, MaxValue: t.MaxValue}); err != nil {
+			problems = append(problems, fmt.Sprintf("thresholds[%d]: %v", i, err))
+		}
+		if seenTypes[t.Type] {
+			problems = append(problems, fmt.Sprintf("thresholds[%d]: duplicate type %q", i, t.Type))
+		}
+		seenTypes[t.Type] = true
+	}
+
+	seenLimits := make(map[string]bool)
+	for i, l := range b.Limits {
+		if l.Equipment == "" || l.Parameter == "" {
+			problems = append(problems, fmt.Sprintf("limits[%d]: equipment and parameter are required", i))
+			continue
+		}
+		// У параметра без датчика проверяются только границы
+		err := validation.Range(l.MinValue, l.MaxValue)
+		if err == nil && l.SensorType != nil {
+			err = validation.SensorType("sensor_type", *l.SensorType)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("limits[%d] %s: %v", i, l.key(), err))
+		}
+		if seenLimits[l.key()] {
+			problems = append(problems, fmt.Sprintf("limits[%d]: duplicate parameter %s", i, l.key()))
+		}
+		seenLimits[l.key()] = true
+	}
+	return problems
+}
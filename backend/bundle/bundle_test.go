@@ -0,0 +1,132 @@
+package bundle_test
+
+import (
+	"bytes"
+	"realtime-app/bundle"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var parameterColumns = []string{"id", "id_equipment", "name", "units", "sensor_type", "min_value", "max_value"}
+
+func sampleBundle() bundle.Bundle {
+	sensor := "pressure"
+	return bundle.Bundle{
+		Version:    bundle.Version,
+		ExportedAt: time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC),
+		Thresholds: []bundle.ThresholdEntry{
+			{Type: "temperature", MinValue: 22, MaxValue: 34},
+			{Type: "humidity", MinValue: 30, MaxValue: 80},
+		},
+		Limits: []bundle.LimitEntry{
+			{Equipment: "Пресс 1", Parameter: "Давление", Units: "hPa", SensorType: &sensor, MinValue: 950, MaxValue: 1050},
+		},
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	for _, format := range []string{bundle.FormatYAML, bundle.FormatJSON} {
+		var buf bytes.Buffer
+		assert.NoError(t, bundle.Encode(&buf, sampleBundle(), format))
+
+		decoded, err := bundle.Decode(&buf, format)
+		assert.NoError(t, err, format)
+		assert.Equal(t, sampleBundle(), decoded, format)
+	}
+}
+
+func TestDecodeRejectsUnknownFields(t *testing.T) {
+	_, err := bundle.Decode(bytes.NewBufferString("version: 1\nthreshold: []\n"), bundle.FormatYAML)
+	assert.Error(t, err)
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	b := sampleBundle()
+	b.Version = 2
+	b.Thresholds = append(b.Thresholds,
+		bundle.ThresholdEntry{Type: "temperature", MinValue: 1, MaxValue: 2},
+		bundle.ThresholdEntry{Type: "vibration", MinValue: 0, MaxValue: 1},
+	)
+	b.Limits[0].MinValue = 2000
+
+	assert.Len(t, bundle.Validate(b), 4)
+	assert.Empty(t, bundle.Validate(sampleBundle()))
+}
+
+func TestDiff(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT type, min_value, max_value FROM thresholds").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "min_value", "max_value"}).
+			AddRow("temperature", 20, 35).
+			AddRow("humidity", 30, 80))
+	// Параметр датчика давления в этой базе назван иначе, чем в выгрузке
+	mock.ExpectQuery("FROM process_parameters pp").
+		WillReturnRows(sqlmock.NewRows(parameterColumns).
+			AddRow(5, 1, "pressure", "hPa", "pressure", 900, 1100))
+	mock.ExpectQuery("SELECT id, name FROM equipment").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Пресс 1"))
+
+	plan, err := bundle.Diff(sqlx.NewDb(db, "sqlmock"), sampleBundle())
+	assert.NoError(t, err)
+	assert.True(t, plan.Valid())
+
+	if assert.Len(t, plan.Changes, 3) {
+		assert.Equal(t, bundle.ActionUpdate, plan.Changes[0].Action)
+		assert.Equal(t, 35.0, *plan.Changes[0].OldMaxValue)
+		assert.Equal(t, bundle.ActionUnchanged, plan.Changes[1].Action)
+		assert.Equal(t, bundle.ActionUpdate, plan.Changes[2].Action)
+		assert.Equal(t, 1100.0, *plan.Changes[2].OldMaxValue)
+	}
+	assert.Equal(t, 2, plan.Pending())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDiffReportsAmbiguousAndConflictingLimits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	sensor := "temperature"
+	b := sampleBundle()
+	b.Thresholds = nil
+	b.Limits = append(b.Limits,
+		bundle.LimitEntry{Equipment: "Печь", Parameter: "Температура", Units: "°C", SensorType: &sensor, MinValue: 100, MaxValue: 200},
+		bundle.LimitEntry{Equipment: "Пресс 1", Parameter: "Усилие", Units: "кН", MinValue: 0, MaxValue: 50},
+	)
+
+	mock.ExpectQuery("SELECT type, min_value, max_value FROM thresholds").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "min_value", "max_value"}))
+	mock.ExpectQuery("FROM process_parameters pp").
+		WillReturnRows(sqlmock.NewRows(parameterColumns).
+			// Имя из выгрузки занято параметром другого датчика
+			AddRow(5, 1, "Давление", "hPa", "humidity", nil, nil).
+			AddRow(6, 1, "pressure", "hPa", "pressure", 900, 1100).
+			AddRow(7, 1, "Усилие", "кН", nil, nil, nil).
+			AddRow(8, 1, "Усилие", "кН", nil, nil, nil))
+	mock.ExpectQuery("SELECT id, name FROM equipment").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).
+			AddRow(1, "Пресс 1").
+			AddRow(2, "Печь").
+			AddRow(3, "Печь"))
+
+	plan, err := bundle.Diff(sqlx.NewDb(db, "sqlmock"), b)
+	assert.NoError(t, err)
+	assert.False(t, plan.Valid())
+	if assert.Len(t, plan.Problems, 3) {
+		assert.Contains(t, plan.Problems[0], `belongs to parameter "pressure"`)
+		assert.Contains(t, plan.Problems[1], `equipment name "Печь" is ambiguous`)
+		assert.Contains(t, plan.Problems[2], `parameter name "Усилие" is ambiguous`)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
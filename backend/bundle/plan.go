@@ -0,0 +1,288 @@
+package bundle
+
+import (
+	"fmt"
+	"io"
+	database "realtime-app/db"
+	"realtime-app/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Действия импорта над записью
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+)
+
+// Виды записей выгрузки
+const (
+	KindThreshold = "threshold"
+	KindLimit     = "limit"
+)
+
+// Change - изменение одной записи при импорте
+type Change struct {
+	Kind        string   `json:"kind"`
+	Key         string   `json:"key"`
+	Action      string   `json:"action"`
+	OldMinValue *float64 `json:"old_min_value,omitempty"`
+	OldMaxValue *float64 `json:"old_max_value,omitempty"`
+	NewMinValue float64  `json:"new_min_value"`
+	NewMaxValue float64  `json:"new_max_value"`
+
+	// Для границ: оборудование и параметр (nil - будет создан), найденные Diff
+	equipmentID int
+	paramID     *int
+}
+
+// parameter - параметр процесса оборудования с границами, если они заданы
+type parameter struct {
+	ID          int      `db:"id"`
+	EquipmentID int      `db:"id_equipment"`
+	Name        string   `db:"name"`
+	Units       string   `db:"units"`
+	SensorType  *string  `db:"sensor_type"`
+	MinValue    *float64 `db:"min_value"`
+	MaxValue    *float64 `db:"max_value"`
+}
+
+// Plan - предварительный просмотр импорта: изменения и причины, по которым
+// выгрузку нельзя применить к этой базе
+type Plan struct {
+	Changes  []Change `json:"changes"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Valid сообщает, можно ли применить план
+func (p Plan) Valid() bool {
+	return len(p.Problems) == 0
+}
+
+// Pending возвращает число записей, которые будут созданы или изменены
+func (p Plan) Pending() int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action != ActionUnchanged {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteText выводит план в виде, удобном для командной строки
+func (p Plan) WriteText(w io.Writer) {
+	marks := map[string]string{ActionCreate: "+", ActionUpdate: "~", ActionUnchanged: "="}
+	for _, c := range p.Changes {
+		fmt.Fprintf(w, "%s %s %s: ", marks[c.Action], c.Kind, c.Key)
+		if c.Action == ActionUpdate {
+			fmt.Fprintf(w, "%g..%g -> ", *c.OldMinValue, *c.OldMaxValue)
+		}
+		fmt.Fprintf(w, "%g..%g\n", c.NewMinValue, c.NewMaxValue)
+	}
+	for _, problem := range p.Problems {
+		fmt.Fprintf(w, "! %s\n", problem)
+	}
+	fmt.Fprintf(w, "%d of %d entries will change\n", p.Pending(), len(p.Changes))
+}
+
+// Diff сравнивает выгрузку с текущим состоянием базы. Записи, которых нет
+// в выгрузке, при импорте не удаляются.
+func Diff(db sqlx.Queryer, b Bundle) (Plan, error) {
+	plan := Plan{Changes: []Change{}, Problems: Validate(b)}
+
+	var thresholds []ThresholdEntry
+	if err := sqlx.Select(db, &thresholds, "SELECT type, min_value, max_value FROM thresholds"); err != nil {
+		return plan, err
+	}
+	currentThresholds := make(map[string]ThresholdEntry)
+	for _, t := range thresholds {
+		currentThresholds[t.Type] = t
+	}
+
+	var params []parameter
+	if err := sqlx.Select(db, &params, `
+		SELECT pp.id, pp.id_equipment, pp.name, pp.units, pp.sensor_type, rp.min_value, rp.max_value
+		FROM process_parameters pp
+		LEFT JOIN reference_parameters rp ON rp.id_param = pp.id
+		WHERE pp.id_equipment IS NOT NULL`); err != nil {
+		return plan, err
+	}
+
+	var equipment []struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	if err := sqlx.Select(db, &equipment, "SELECT id, name FROM equipment"); err != nil {
+		return plan, err
+	}
+	equipmentIDs := make(map[string][]int)
+	for _, e := range equipment {
+		equipmentIDs[e.Name] = append(equipmentIDs[e.Name], e.ID)
+	}
+
+	for _, t := range b.Thresholds {
+		change := Change{Kind: KindThreshold, Key: t.Type, Action: ActionCreate, NewMinValue: t.MinValue, NewMaxValue: t.MaxValue}
+		if current, ok := currentThresholds[t.Type]; ok {
+			change.setOld(current.MinValue, current.MaxValue)
+			if current == t {
+				change.Action = ActionUnchanged
+			}
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	for _, l := range b.Limits {
+		ids := equipmentIDs[l.Equipment]
+		switch len(ids) {
+		case 0:
+			plan.Problems = append(plan.Problems, fmt.Sprintf("limit %s: unknown equipment %q", l.key(), l.Equipment))
+			continue
+		case 1:
+		default:
+			plan.Problems = append(plan.Problems, fmt.Sprintf("limit %s: equipment name %q is ambiguous", l.key(), l.Equipment))
+			continue
+		}
+
+		param, problem := resolveParameter(params, ids[0], l)
+		if problem != "" {
+			plan.Problems = append(plan.Problems, fmt.Sprintf("limit %s: %s", l.key(), problem))
+			continue
+		}
+
+		change := Change{Kind: KindLimit, Key: l.key(), Action: ActionCreate, NewMinValue: l.MinValue, NewMaxValue: l.MaxValue, equipmentID: ids[0]}
+		if param != nil {
+			change.paramID = &param.ID
+			if param.MinValue != nil && param.MaxValue != nil {
+				change.setOld(*param.MinValue, *param.MaxValue)
+				if sameLimit(*param, l) {
+					change.Action = ActionUnchanged
+				}
+			}
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	return plan, nil
+}
+
+func (c *Change) setOld(minValue, maxValue float64) {
+	c.Action = ActionUpdate
+	c.OldMinValue = &minValue
+	c.OldMaxValue = &maxValue
+}
+
+// resolveParameter находит параметр оборудования для границ l: по типу
+// датчика, если он указан (имена параметров в разных базах могут отличаться,
+// а датчик у оборудования один), иначе по имени. Возвращает nil, если параметр
+// нужно создать, или описание конфликта.
+func resolveParameter(params []parameter, equipmentID int, l LimitEntry) (*parameter, string) {
+	var byName []*parameter
+	var bySensor *parameter
+	for i := range params {
+		p := &params[i]
+		if p.EquipmentID != equipmentID {
+			continue
+		}
+		if p.Name == l.Parameter {
+			byName = append(byName, p)
+		}
+		if l.SensorType != nil && p.SensorType != nil && *p.SensorType == *l.SensorType {
+			bySensor = p
+		}
+	}
+
+	if bySensor != nil {
+		for _, p := range byName {
+			if p != bySensor {
+				return nil, fmt.Sprintf("sensor %s belongs to parameter %q, not to parameter %q", *l.SensorType, bySensor.Name, l.Parameter)
+			}
+		}
+		return bySensor, ""
+	}
+	switch len(byName) {
+	case 0:
+		return nil, ""
+	case 1:
+		return byName[0], ""
+	default:
+		return nil, fmt.Sprintf("parameter name %q is ambiguous", l.Parameter)
+	}
+}
+
+func sameLimit(p parameter, l LimitEntry) bool {
+	sameSensor := (p.SensorType == nil && l.SensorType == nil) ||
+		(p.SensorType != nil && l.SensorType != nil && *p.SensorType == *l.SensorType)
+	return sameSensor && p.Units == l.Units && *p.MinValue == l.MinValue && *p.MaxValue == l.MaxValue
+}
+
+// Apply применяет выгрузку в одной транзакции. Изменения порогов попадают
+// в threshold_history с указанным автором и причиной. Если план содержит
+// ошибки, база не изменяется. Возвращает план и сохраненные пороги.
+func Apply(db *sqlx.DB, b Bundle, change models.ThresholdChange) (Plan, []models.Threshold, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return Plan{}, nil, err
+	}
+	defer tx.Rollback()
+
+	plan, err := Diff(tx, b)
+	if err != nil || !plan.Valid() {
+		return plan, nil, err
+	}
+
+	var saved []models.Threshold
+	for i, t := range b.Thresholds {
+		if plan.Changes[i].Action == ActionUnchanged {
+			continue
+		}
+		threshold, err := database.SaveThresholdTx(tx, models.Threshold{Type: t.Type, MinValue: t.MinValue, MaxValue: t.MaxValue}, change)
+		if err != nil {
+			return plan, nil, fmt.Errorf("threshold %s: %v", t.Type, err)
+		}
+		saved = append(saved, threshold)
+	}
+
+	for i, l := range b.Limits {
+		if plan.Changes[len(b.Thresholds)+i].Action == ActionUnchanged {
+			continue
+		}
+		if err := saveLimit(tx, l, plan.Changes[len(b.Thresholds)+i]); err != nil {
+			return plan, nil, fmt.Errorf("limit %s: %v", l.key(), err)
+		}
+	}
+
+	return plan, saved, tx.Commit()
+}
+
+// saveLimit создает или обновляет найденный Diff параметр оборудования
+// и записывает его границы. Имя существующего параметра не меняется.
+func saveLimit(tx *sqlx.Tx, l LimitEntry, c Change) error {
+	var paramID int
+	var err error
+	if c.paramID == nil {
+		err = tx.Get(&paramID, `
+			INSERT INTO process_parameters (id_equipment, name, units, sensor_type)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id`,
+			c.equipmentID, l.Parameter, l.Units, l.SensorType)
+	} else {
+		paramID = *c.paramID
+		_, err = tx.Exec("UPDATE process_parameters SET units = $1, sensor_type = $2 WHERE id = $3",
+			l.Units, l.SensorType, paramID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO reference_parameters (id_param, min_value, max_value)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id_param) DO UPDATE SET
+			min_value = EXCLUDED.min_value,
+			max_value = EXCLUDED.max_value,
+			template_id = NULL`,
+		paramID, l.MinValue, l.MaxValue)
+	return err
+}
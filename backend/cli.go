@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"realtime-app/bundle"
+	"realtime-app/models"
+	"strings"
+)
+
+// runCommand выполняет служебную команду вместо запуска сервера:
+//
+//	backend export [-format yaml|json] [-o file]
+//	backend import [-format yaml|json] [-dry-run] [-user name] [-reason text] file
+func runCommand(args []string) error {
+	switch args[0] {
+	case "export":
+		return exportCommand(args[1:])
+	case "import":
+		return importCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q (expected export or import)", args[0])
+	}
+}
+
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	formatName := flags.String("format", bundle.FormatYAML, "bundle format: yaml or json")
+	output := flags.String("o", "", "output file (stdout by default)")
+	flags.Parse(args)
+
+	format, err := bundle.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	dbConn, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	b, err := bundle.Export(dbConn)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return bundle.Encode(w, b, format)
+}
+
+func importCommand(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	formatName := flags.String("format", "", "bundle format: yaml or json (by file extension by default)")
+	dryRun := flags.Bool("dry-run", false, "only print the changes")
+	user := flags.String("user", os.Getenv("USER"), "author recorded in threshold history")
+	reason := flags.String("reason", "", "reason recorded in threshold history")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: import [flags] file")
+	}
+	path := flags.Arg(0)
+
+	if *formatName == "" && strings.HasSuffix(path, ".json") {
+		*formatName = bundle.FormatJSON
+	}
+	format, err := bundle.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b, err := bundle.Decode(f, format)
+	if err != nil {
+		return err
+	}
+
+	dbConn, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	if *dryRun {
+		plan, err := bundle.Diff(dbConn, b)
+		if err != nil {
+			return err
+		}
+		plan.WriteText(os.Stdout)
+		if !plan.Valid() {
+			return fmt.Errorf("bundle can't be applied")
+		}
+		return nil
+	}
+
+	if *user == "" {
+		*user = "cli"
+	}
+	if *reason == "" {
+		*reason = "import " + path
+	}
+	// Работающие экземпляры подхватят изменения через LISTEN/NOTIFY
+	plan, _, err := bundle.Apply(dbConn, b, models.ThresholdChange{User: *user, Reason: *reason})
+	if err != nil {
+		return err
+	}
+	plan.WriteText(os.Stdout)
+	if !plan.Valid() {
+		return fmt.Errorf("bundle can't be applied, nothing was changed")
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package db
+
+import "realtime-app/models"
+
+// DefaultThresholds - общие пороги по типу датчика для новой установки.
+// Ими заполняется пустая таблица thresholds и хранилище порогов до загрузки из БД.
+var DefaultThresholds = defaultThresholds()
+
+func defaultThresholds() []models.Threshold {
+	var thresholds []models.Threshold
+	for _, t := range models.SensorTypes() {
+		thresholds = append(thresholds, t.DefaultThreshold())
+	}
+	return thresholds
+}
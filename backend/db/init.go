@@ -18,6 +18,16 @@ func InitDB(db *sqlx.DB) error {
 		return fmt.Errorf("ошибка инициализации оборудования: %v", err)
 	}
 
+	// Привязка датчиков к оборудованию
+	if err := initSensorEquipment(db); err != nil {
+		return fmt.Errorf("ошибка привязки датчиков к оборудованию: %v", err)
+	}
+
+	// Параметры процесса для датчиков: к ним привязываются границы по оборудованию
+	if err := initSensorParameters(db); err != nil {
+		return fmt.Errorf("ошибка инициализации параметров процесса: %v", err)
+	}
+
 	// Инициализация пороговых значений
 	if err := initDefaultThresholds(db); err != nil {
 		return fmt.Errorf("ошибка инициализации порогов: %v", err)
@@ -49,22 +59,53 @@ func InitDefaultEquipment(db *sqlx.DB) error {
 	return nil
 }
 
-// Инициализация пороговых значений
-func initDefaultThresholds(db *sqlx.DB) error {
-	defaults := []models.Threshold{
-		{Type: "temperature", MinValue: 20, MaxValue: 100},
-		{Type: "humidity", MinValue: 30, MaxValue: 80},
-		{Type: "pressure", MinValue: 900, MaxValue: 1100},
+// Привязка датчиков к оборудованию по умолчанию. Существующая привязка не меняется.
+func initSensorEquipment(db *sqlx.DB) error {
+	defaults := []struct {
+		Type      string
+		Equipment string
+	}{
+		{"temperature", "Датчик температуры"},
+		{"humidity", "Пресс 1"},
+		{"pressure", "Пресс 1"},
 	}
 
-	for _, t := range defaults {
+	for _, d := range defaults {
+		_, err := db.Exec(`
+			INSERT INTO sensor_equipment (type, id_equipment)
+			SELECT $1, id FROM equipment WHERE name = $2 ORDER BY id LIMIT 1
+			ON CONFLICT (type) DO NOTHING`,
+			d.Type, d.Equipment)
+		if err != nil {
+			return fmt.Errorf("ошибка привязки датчика %s: %v", d.Type, err)
+		}
+	}
+	return nil
+}
+
+// Создание параметров процесса для привязанных датчиков
+func initSensorParameters(db *sqlx.DB) error {
+	for _, sensorType := range models.SensorTypes() {
+		name, units := sensorType.Parameter()
+		_, err := db.Exec(`
+			INSERT INTO process_parameters (id_equipment, name, units, sensor_type)
+			SELECT id_equipment, $2, $3, type FROM sensor_equipment WHERE type = $1
+			ON CONFLICT (id_equipment, sensor_type) DO NOTHING`,
+			sensorType.String(), name, units)
+		if err != nil {
+			return fmt.Errorf("ошибка создания параметра %s: %v", sensorType, err)
+		}
+	}
+	return nil
+}
+
+// Заполнение порогов значениями по умолчанию. Настроенные пороги не меняются.
+func initDefaultThresholds(db *sqlx.DB) error {
+	for _, t := range DefaultThresholds {
 		_, err := db.Exec(`
 			INSERT INTO thresholds (type, min_value, max_value)
 			VALUES ($1, $2, $3)
-			ON CONFLICT (type) DO UPDATE SET 
-				min_value = EXCLUDED.min_value, 
-				max_value = EXCLUDED.max_value,
-				updated_at = CURRENT_TIMESTAMP`,
+			ON CONFLICT (type) DO NOTHING`,
 			t.Type, t.MinValue, t.MaxValue)
 		if err != nil {
 			return fmt.Errorf("ошибка инициализации порога для %s: %v", t.Type, err)
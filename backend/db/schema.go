@@ -35,12 +35,35 @@ func CreateTables(db *sqlx.DB) error {
     CREATE INDEX IF NOT EXISTS idx_current_params_timestamp ON current_parameters (timestamp);
     CREATE INDEX IF NOT EXISTS idx_current_params_id ON current_parameters (id_param);
 
+	-- Показания хранятся в суточных секциях sensor_data_pГГГГММДД. Таблица,
+	-- созданная до секционирования, переименовывается в sensor_data_legacy
+	-- и ниже подключается к новой таблице как одна секция.
+	DO $$
+	BEGIN
+		IF EXISTS (SELECT 1 FROM pg_class WHERE oid = to_regclass('sensor_data') AND relkind = 'r') THEN
+			ALTER TABLE sensor_data RENAME TO sensor_data_legacy;
+			-- В исходной схеме привязки к оборудованию еще нет, а ALTER ниже
+			-- применяется уже к новой таблице
+			ALTER TABLE sensor_data_legacy ADD COLUMN IF NOT EXISTS id_equipment INT REFERENCES equipment(id) ON DELETE SET NULL;
+			ALTER TABLE sensor_data_legacy DROP CONSTRAINT IF EXISTS sensor_data_pkey;
+			DROP INDEX IF EXISTS idx_sensor_data_type, idx_sensor_data_timestamp, idx_sensor_data_type_timestamp;
+			DELETE FROM sensor_data_legacy WHERE timestamp IS NULL;
+			ALTER TABLE sensor_data_legacy ALTER COLUMN timestamp SET NOT NULL;
+		END IF;
+	END
+	$$;
+
+	CREATE SEQUENCE IF NOT EXISTS sensor_data_id_seq;
+
 	CREATE TABLE IF NOT EXISTS sensor_data (
-		id SERIAL PRIMARY KEY,
+		id INT NOT NULL DEFAULT nextval('sensor_data_id_seq'),
 		value DOUBLE PRECISION NOT NULL,
 		type TEXT NOT NULL,
-		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
+		timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (id, timestamp)
+	) PARTITION BY RANGE (timestamp);
+
+	ALTER SEQUENCE sensor_data_id_seq OWNED BY sensor_data.id;
 	
 	CREATE TABLE IF NOT EXISTS thresholds (
 		id SERIAL PRIMARY KEY,
@@ -51,8 +74,327 @@ func CreateTables(db *sqlx.DB) error {
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 	
+	ALTER TABLE thresholds ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+	CREATE TABLE IF NOT EXISTS threshold_history (
+		id SERIAL PRIMARY KEY,
+		type TEXT NOT NULL,
+		old_min_value DOUBLE PRECISION,
+		old_max_value DOUBLE PRECISION,
+		new_min_value DOUBLE PRECISION NOT NULL,
+		new_max_value DOUBLE PRECISION NOT NULL,
+		changed_by TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		rollback_of INT REFERENCES threshold_history(id),
+		changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_threshold_history_type ON threshold_history (type, changed_at);
+
+	CREATE TABLE IF NOT EXISTS threshold_profiles (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS threshold_profile_values (
+		profile_id INT NOT NULL REFERENCES threshold_profiles(id) ON DELETE CASCADE,
+		type TEXT NOT NULL,
+		min_value DOUBLE PRECISION NOT NULL,
+		max_value DOUBLE PRECISION NOT NULL,
+		PRIMARY KEY (profile_id, type)
+	);
+
+	-- Расписание профилей: дни недели битовой маской (бит 0 - воскресенье),
+	-- интервал времени (end_time < start_time - через полночь), приоритет
+	-- при пересечении и необязательный диапазон дат
+	CREATE TABLE IF NOT EXISTS threshold_profile_schedule (
+		id SERIAL PRIMARY KEY,
+		profile_id INT NOT NULL REFERENCES threshold_profiles(id) ON DELETE CASCADE,
+		weekdays INT NOT NULL DEFAULT 127 CHECK (weekdays BETWEEN 1 AND 127),
+		start_time TIME NOT NULL,
+		end_time TIME NOT NULL,
+		priority INT NOT NULL DEFAULT 0,
+		valid_from DATE,
+		valid_to DATE
+	);
+
+	-- Активный профиль, общий для всех экземпляров
+	CREATE TABLE IF NOT EXISTS active_threshold_profile (
+		id INT PRIMARY KEY CHECK (id = 1),
+		profile_id INT REFERENCES threshold_profiles(id) ON DELETE SET NULL,
+		switched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	INSERT INTO active_threshold_profile (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+	-- Оповещение всех экземпляров backend об изменении порога
+	CREATE OR REPLACE FUNCTION notify_threshold_change() RETURNS trigger AS $$
+	BEGIN
+		PERFORM pg_notify('thresholds_changed', NEW.type);
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS thresholds_notify ON thresholds;
+	CREATE TRIGGER thresholds_notify
+		AFTER INSERT OR UPDATE ON thresholds
+		FOR EACH ROW EXECUTE FUNCTION notify_threshold_change();
+
+	-- Границы по оборудованию: параметр процесса привязан к типу датчика,
+	-- а reference_parameters хранит для него собственные min/max
+	ALTER TABLE process_parameters ADD COLUMN IF NOT EXISTS sensor_type TEXT;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_process_parameters_sensor ON process_parameters (id_equipment, sensor_type);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_reference_parameters_param ON reference_parameters (id_param);
+	ALTER TABLE sensor_data ADD COLUMN IF NOT EXISTS id_equipment INT REFERENCES equipment(id) ON DELETE SET NULL;
+
+	CREATE OR REPLACE FUNCTION notify_limits_change() RETURNS trigger AS $$
+	BEGIN
+		PERFORM pg_notify('limits_changed', '');
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	-- Шаблоны границ по типу оборудования. Границы, созданные из шаблона,
+	-- ссылаются на него; ручное изменение обнуляет ссылку и сохраняется
+	-- при повторном применении шаблона.
+	CREATE TABLE IF NOT EXISTS threshold_templates (
+		id SERIAL PRIMARY KEY,
+		equipment_type VARCHAR(50) NOT NULL,
+		sensor_type TEXT NOT NULL,
+		min_value DOUBLE PRECISION NOT NULL,
+		max_value DOUBLE PRECISION NOT NULL,
+		UNIQUE (equipment_type, sensor_type)
+	);
+
+	ALTER TABLE reference_parameters ADD COLUMN IF NOT EXISTS template_id INT REFERENCES threshold_templates(id) ON DELETE SET NULL;
+
+	DROP TRIGGER IF EXISTS reference_parameters_notify ON reference_parameters;
+	CREATE TRIGGER reference_parameters_notify
+		AFTER INSERT OR UPDATE OR DELETE ON reference_parameters
+		FOR EACH STATEMENT EXECUTE FUNCTION notify_limits_change();
+
 	CREATE INDEX IF NOT EXISTS idx_sensor_data_type ON sensor_data (type);
-	CREATE INDEX IF NOT EXISTS idx_sensor_data_timestamp ON sensor_data (timestamp);`
+	CREATE INDEX IF NOT EXISTS idx_sensor_data_timestamp ON sensor_data (timestamp);
+	CREATE INDEX IF NOT EXISTS idx_sensor_data_type_timestamp ON sensor_data (type, timestamp, id);
+
+	-- Суточные секции показаний начиная с first_day
+	CREATE OR REPLACE FUNCTION create_sensor_data_partitions(first_day DATE, days INT) RETURNS void AS $$
+	DECLARE
+		d DATE;
+	BEGIN
+		FOR i IN 0..days - 1 LOOP
+			d := first_day + i;
+			EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF sensor_data FOR VALUES FROM (%L) TO (%L)',
+				'sensor_data_p' || to_char(d, 'YYYYMMDD'), d, d + 1);
+		END LOOP;
+	END;
+	$$ LANGUAGE plpgsql;
+
+	-- Старая таблица становится секцией до начала текущих суток,
+	-- показания за сегодня переносятся в суточную секцию
+	DO $$
+	DECLARE
+		today DATE := CURRENT_DATE;
+		last_day DATE;
+	BEGIN
+		IF to_regclass('sensor_data_legacy') IS NOT NULL AND NOT EXISTS (
+			SELECT 1 FROM pg_inherits WHERE inhrelid = to_regclass('sensor_data_legacy')
+		) THEN
+			SELECT GREATEST(MAX(timestamp)::date, today) INTO last_day FROM sensor_data_legacy;
+			PERFORM create_sensor_data_partitions(today, last_day - today + 1);
+			INSERT INTO sensor_data (id, value, type, timestamp, id_equipment)
+				SELECT id, value, type, timestamp, id_equipment FROM sensor_data_legacy WHERE timestamp >= today;
+			DELETE FROM sensor_data_legacy WHERE timestamp >= today;
+			EXECUTE format('ALTER TABLE sensor_data ATTACH PARTITION sensor_data_legacy FOR VALUES FROM (MINVALUE) TO (%L)', today);
+		END IF;
+	END
+	$$;
+
+	-- Секции на ближайшую неделю; дальше их создает задача хранения
+	SELECT create_sensor_data_partitions(CURRENT_DATE, 7);
+
+	CREATE TABLE IF NOT EXISTS sensor_rollup_1m (
+		type TEXT NOT NULL,
+		equipment_id INT NOT NULL DEFAULT 0,
+		bucket TIMESTAMP NOT NULL,
+		min DOUBLE PRECISION NOT NULL,
+		max DOUBLE PRECISION NOT NULL,
+		sum DOUBLE PRECISION NOT NULL,
+		count BIGINT NOT NULL,
+		first DOUBLE PRECISION NOT NULL,
+		last DOUBLE PRECISION NOT NULL,
+		PRIMARY KEY (type, equipment_id, bucket)
+	);
+
+	CREATE TABLE IF NOT EXISTS sensor_rollup_1h (
+		type TEXT NOT NULL,
+		equipment_id INT NOT NULL DEFAULT 0,
+		bucket TIMESTAMP NOT NULL,
+		min DOUBLE PRECISION NOT NULL,
+		max DOUBLE PRECISION NOT NULL,
+		sum DOUBLE PRECISION NOT NULL,
+		count BIGINT NOT NULL,
+		first DOUBLE PRECISION NOT NULL,
+		last DOUBLE PRECISION NOT NULL,
+		PRIMARY KEY (type, equipment_id, bucket)
+	);
+
+	CREATE TABLE IF NOT EXISTS sensor_rollup_1d (
+		type TEXT NOT NULL,
+		equipment_id INT NOT NULL DEFAULT 0,
+		bucket TIMESTAMP NOT NULL,
+		min DOUBLE PRECISION NOT NULL,
+		max DOUBLE PRECISION NOT NULL,
+		sum DOUBLE PRECISION NOT NULL,
+		count BIGINT NOT NULL,
+		first DOUBLE PRECISION NOT NULL,
+		last DOUBLE PRECISION NOT NULL,
+		PRIMARY KEY (type, equipment_id, bucket)
+	);
+
+	CREATE TABLE IF NOT EXISTS sensor_rollup_state (
+		resolution TEXT PRIMARY KEY,
+		rolled_up_to TIMESTAMP
+	);
+
+	INSERT INTO sensor_rollup_state (resolution) VALUES ('1m'), ('1h'), ('1d')
+	ON CONFLICT (resolution) DO NOTHING;
+
+	CREATE TABLE IF NOT EXISTS alarms (
+		id SERIAL PRIMARY KEY,
+		type TEXT NOT NULL,
+		state TEXT NOT NULL CHECK (state IN ('active', 'cleared')),
+		direction TEXT NOT NULL CHECK (direction IN ('high', 'low')),
+		value DOUBLE PRECISION NOT NULL,
+		limit_value DOUBLE PRECISION NOT NULL,
+		raised_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		cleared_at TIMESTAMP,
+		acknowledged_at TIMESTAMP,
+		acknowledged_by TEXT,
+		equipment_id INT REFERENCES equipment(id) ON DELETE SET NULL,
+		shelved BOOLEAN NOT NULL DEFAULT FALSE,
+		suppressed BOOLEAN NOT NULL DEFAULT FALSE,
+		escalation_tier INT NOT NULL DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alarms_state ON alarms (state);
+
+	-- Тревоги по прогнозу выхода за границу; по датчику активна не больше
+	-- одной, даже если прогноз проверяют несколько экземпляров
+	ALTER TABLE alarms ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'limit' CHECK (kind IN ('limit', 'predicted'));
+	ALTER TABLE alarms ADD COLUMN IF NOT EXISTS predicted_at TIMESTAMP;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_alarms_predicted_active ON alarms (type) WHERE kind = 'predicted' AND state = 'active';
+	CREATE INDEX IF NOT EXISTS idx_alarms_raised_at ON alarms (raised_at);
+
+	CREATE TABLE IF NOT EXISTS alarm_comments (
+		id SERIAL PRIMARY KEY,
+		alarm_id INT NOT NULL REFERENCES alarms(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL CHECK (kind IN ('ack', 'comment')),
+		author TEXT NOT NULL,
+		body TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alarm_comments_alarm ON alarm_comments (alarm_id);
+
+	CREATE TABLE IF NOT EXISTS sensor_equipment (
+		type TEXT PRIMARY KEY,
+		id_equipment INT NOT NULL REFERENCES equipment(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS alarm_shelves (
+		id SERIAL PRIMARY KEY,
+		type TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		shelved_by TEXT NOT NULL,
+		shelved_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		unshelved_at TIMESTAMP,
+		unshelved_by TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alarm_shelves_type ON alarm_shelves (type);
+
+	CREATE TABLE IF NOT EXISTS notification_channels (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		kind TEXT NOT NULL CHECK (kind IN ('webhook', 'email')),
+		target TEXT NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		tier INT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS escalation_policies (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		type TEXT,
+		tier INT NOT NULL CHECK (tier > 0),
+		delay_seconds INT NOT NULL CHECK (delay_seconds > 0),
+		enabled BOOLEAN NOT NULL DEFAULT TRUE
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_escalation_policies_tier ON escalation_policies (tier, COALESCE(type, ''));
+
+	CREATE TABLE IF NOT EXISTS alarm_escalations (
+		id SERIAL PRIMARY KEY,
+		alarm_id INT NOT NULL REFERENCES alarms(id) ON DELETE CASCADE,
+		policy_id INT REFERENCES escalation_policies(id) ON DELETE SET NULL,
+		tier INT NOT NULL,
+		recipients TEXT NOT NULL DEFAULT '',
+		escalated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alarm_escalations_alarm ON alarm_escalations (alarm_id);
+
+	CREATE TABLE IF NOT EXISTS notification_log (
+		id SERIAL PRIMARY KEY,
+		channel_id INT REFERENCES notification_channels(id) ON DELETE CASCADE,
+		alarm_id INT REFERENCES alarms(id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		attempt INT NOT NULL,
+		status TEXT NOT NULL CHECK (status IN ('sent', 'failed')),
+		error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_notification_log_alarm ON notification_log (alarm_id);
+
+	-- Аномалии живого потока; sensor_data_id без внешнего ключа, так как
+	-- секции sensor_data удаляются по сроку хранения
+	CREATE TABLE IF NOT EXISTS anomalies (
+		id SERIAL PRIMARY KEY,
+		type TEXT NOT NULL,
+		equipment_id INT REFERENCES equipment(id) ON DELETE SET NULL,
+		sensor_data_id INT,
+		value DOUBLE PRECISION NOT NULL,
+		expected DOUBLE PRECISION NOT NULL,
+		stddev DOUBLE PRECISION NOT NULL,
+		score DOUBLE PRECISION NOT NULL,
+		method TEXT NOT NULL CHECK (method IN ('ewma', 'seasonal')),
+		detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_anomalies_detected ON anomalies (detected_at);
+
+	-- Ожидаемый период показаний; для датчиков без записи действует значение по умолчанию
+	CREATE TABLE IF NOT EXISTS sensor_intervals (
+		type TEXT PRIMARY KEY,
+		expected_seconds INT NOT NULL CHECK (expected_seconds > 0)
+	);
+
+	CREATE TABLE IF NOT EXISTS communication_losses (
+		id SERIAL PRIMARY KEY,
+		type TEXT NOT NULL,
+		equipment_id INT REFERENCES equipment(id) ON DELETE SET NULL,
+		last_reading_at TIMESTAMP NOT NULL,
+		detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		restored_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_communication_losses_open ON communication_losses (type) WHERE restored_at IS NULL;`
 
 	_, err := db.Exec(schema)
 	return err
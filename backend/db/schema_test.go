@@ -0,0 +1,117 @@
+package db_test
+
+import (
+	"fmt"
+	"os"
+	"realtime-app/db"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// Схема до секционирования sensor_data и привязки показаний к оборудованию
+const baselineSchema = `
+	CREATE TABLE IF NOT EXISTS equipment (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		type VARCHAR(50) NOT NULL,
+		status VARCHAR(20) CHECK (status IN ('Рабочее', 'Неисправное', 'В ремонте'))
+	);
+
+	CREATE TABLE IF NOT EXISTS process_parameters (
+		id SERIAL PRIMARY KEY,
+		id_equipment INT REFERENCES equipment(id),
+		name VARCHAR(60) NOT NULL,
+		units VARCHAR(60) NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS reference_parameters (
+		id SERIAL PRIMARY KEY,
+		id_param INT REFERENCES process_parameters(id),
+		min_value FLOAT NOT NULL,
+		max_value FLOAT NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS current_parameters (
+		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		id_param INT REFERENCES process_parameters(id),
+		value FLOAT NOT NULL,
+		PRIMARY KEY (timestamp, id_param)
+	);
+
+	CREATE TABLE IF NOT EXISTS sensor_data (
+		id SERIAL PRIMARY KEY,
+		value DOUBLE PRECISION NOT NULL,
+		type TEXT NOT NULL,
+		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS thresholds (
+		id SERIAL PRIMARY KEY,
+		type TEXT NOT NULL UNIQUE,
+		min_value DOUBLE PRECISION NOT NULL,
+		max_value DOUBLE PRECISION NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sensor_data_type ON sensor_data (type);
+	CREATE INDEX IF NOT EXISTS idx_sensor_data_timestamp ON sensor_data (timestamp);`
+
+// TestCreateTablesUpgradesBaseline обновляет исходную схему до текущей.
+// Нужен PostgreSQL: строка подключения задается TEST_DATABASE_URL,
+// тест работает в отдельной схеме и удаляет ее после себя.
+func TestCreateTablesUpgradesBaseline(t *testing.T) {
+	connStr := os.Getenv("TEST_DATABASE_URL")
+	if connStr == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	conn, err := sqlx.Connect("postgres", connStr)
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	defer conn.Close()
+	// search_path задается для соединения, поэтому соединение одно
+	conn.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf("migration_test_%d", time.Now().UnixNano())
+	if _, err := conn.Exec(fmt.Sprintf("CREATE SCHEMA %s; SET search_path TO %s", schema, schema)); err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+	defer conn.Exec(fmt.Sprintf("SET search_path TO DEFAULT; DROP SCHEMA %s CASCADE", schema))
+
+	if _, err := conn.Exec(baselineSchema); err != nil {
+		t.Fatalf("Failed to create baseline schema: %v", err)
+	}
+	if _, err := conn.Exec(`
+		INSERT INTO sensor_data (value, type, timestamp) VALUES
+			(20.5, 'temperature', LOCALTIMESTAMP - INTERVAL '3 days'),
+			(21.0, 'temperature', LOCALTIMESTAMP - INTERVAL '1 day'),
+			(1.2, 'pressure', LOCALTIMESTAMP)`); err != nil {
+		t.Fatalf("Failed to insert baseline readings: %v", err)
+	}
+
+	// Повторный запуск не должен ничего менять
+	assert.NoError(t, db.CreateTables(conn))
+	assert.NoError(t, db.CreateTables(conn))
+
+	var count int
+	assert.NoError(t, conn.Get(&count, "SELECT COUNT(*) FROM sensor_data"))
+	assert.Equal(t, 3, count)
+	assert.NoError(t, conn.Get(&count, "SELECT COUNT(*) FROM sensor_data_legacy"))
+	assert.Equal(t, 2, count)
+
+	var attached bool
+	assert.NoError(t, conn.Get(&attached,
+		"SELECT EXISTS (SELECT 1 FROM pg_inherits WHERE inhrelid = to_regclass('sensor_data_legacy'))"))
+	assert.True(t, attached)
+
+	// Новые показания получают следующие id и попадают в суточную секцию
+	var id int
+	assert.NoError(t, conn.Get(&id,
+		"INSERT INTO sensor_data (value, type, id_equipment) VALUES (22, 'temperature', NULL) RETURNING id"))
+	assert.Equal(t, 4, id)
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"realtime-app/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Оборудование и шаблоны его типа
+const templateTargets = `
+	FROM equipment e
+	JOIN threshold_templates t ON t.equipment_type = e.type`
+
+// Отбор оборудования: $1 - тип оборудования, $2 - id оборудования (NULL - все)
+const templateFilter = `
+	WHERE ($1::text IS NULL OR e.type = $1)
+	  AND ($2::int IS NULL OR e.id = $2)`
+
+// ApplyTemplates применяет шаблоны границ к оборудованию: создает недостающие
+// параметры процесса и границы, обновляет границы, созданные из шаблона.
+// Границы, заданные вручную (template_id IS NULL), не меняются.
+// equipmentType и equipmentID сужают набор оборудования; nil - без фильтра.
+func ApplyTemplates(tx *sqlx.Tx, equipmentType *string, equipmentID *int) (models.TemplateApplyResult, error) {
+	var result models.TemplateApplyResult
+
+	for _, sensorType := range models.SensorTypes() {
+		name, units := sensorType.Parameter()
+		if _, err := tx.Exec(`
+			INSERT INTO process_parameters (id_equipment, name, units, sensor_type)
+			SELECT e.id, $4, $5, t.sensor_type`+templateTargets+templateFilter+`
+			  AND t.sensor_type = $3
+			ON CONFLICT (id_equipment, sensor_type) DO NOTHING`,
+			equipmentType, equipmentID, sensorType.String(), name, units); err != nil {
+			return result, err
+		}
+	}
+
+	if err := tx.Get(&result, `
+		SELECT COUNT(*) AS total,
+		       COUNT(rp.id) FILTER (WHERE rp.template_id IS NULL) AS preserved
+		FROM (SELECT e.id AS equipment_id, t.sensor_type`+templateTargets+templateFilter+`) target
+		JOIN process_parameters pp ON pp.id_equipment = target.equipment_id AND pp.sensor_type = target.sensor_type
+		LEFT JOIN reference_parameters rp ON rp.id_param = pp.id`,
+		equipmentType, equipmentID); err != nil {
+		return result, err
+	}
+
+	// xmax = 0 у вставленных строк, у обновленных - id транзакции
+	var created []bool
+	if err := tx.Select(&created, `
+		INSERT INTO reference_parameters (id_param, min_value, max_value, template_id)
+		SELECT pp.id, t.min_value, t.max_value, t.id`+templateTargets+`
+		JOIN process_parameters pp ON pp.id_equipment = e.id AND pp.sensor_type = t.sensor_type`+templateFilter+`
+		ON CONFLICT (id_param) DO UPDATE SET
+			min_value = EXCLUDED.min_value,
+			max_value = EXCLUDED.max_value,
+			template_id = EXCLUDED.template_id
+		WHERE reference_parameters.template_id IS NOT NULL
+		RETURNING xmax = 0`,
+		equipmentType, equipmentID); err != nil {
+		return result, err
+	}
+	for _, c := range created {
+		if c {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+	return result, nil
+}
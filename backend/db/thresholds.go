@@ -1,33 +1,83 @@
 package db
 
 import (
+	"database/sql"
+	"fmt"
+	"realtime-app/models"
+
 	"github.com/jmoiron/sqlx"
 )
 
-// InitDefaultThresholds устанавливает начальные пороговые значения
-func InitDefaultThresholds(db *sqlx.DB) error {
-	defaults := []struct {
-		Type     string
-		MinValue float64
-		MaxValue float64
-	}{
-		{"temperature", 20, 100},
-		{"humidity", 30, 80},
-		{"pressure", 900, 1100},
+// VersionConflictError - порог изменен после того, как клиент прочитал версию
+type VersionConflictError struct {
+	Current models.Threshold
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("threshold %s was modified: current version is %d", e.Current.Type, e.Current.Version)
+}
+
+// SaveThreshold записывает порог и фиксирует изменение в threshold_history
+// в одной транзакции. Возвращает сохраненную строку thresholds.
+func SaveThreshold(db *sqlx.DB, t models.Threshold, change models.ThresholdChange) (models.Threshold, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return models.Threshold{}, err
+	}
+	defer tx.Rollback()
+
+	saved, err := SaveThresholdTx(tx, t, change)
+	if err != nil {
+		return saved, err
 	}
+	return saved, tx.Commit()
+}
 
-	for _, t := range defaults {
-		_, err := db.Exec(`
-            INSERT INTO thresholds (type, min_value, max_value)
-            VALUES ($1, $2, $3)
-            ON CONFLICT (type) DO UPDATE SET 
-                min_value = EXCLUDED.min_value, 
-                max_value = EXCLUDED.max_value,
-                updated_at = CURRENT_TIMESTAMP`,
-			t.Type, t.MinValue, t.MaxValue)
-		if err != nil {
-			return err
+// SaveThresholdTx - SaveThreshold внутри уже открытой транзакции.
+// Если задан change.IfVersion и версия в БД другая, возвращает *VersionConflictError.
+func SaveThresholdTx(tx *sqlx.Tx, t models.Threshold, change models.ThresholdChange) (models.Threshold, error) {
+	var saved models.Threshold
+
+	// Блокируем строку, чтобы параллельные изменения не перепутали старые значения
+	var old struct {
+		MinValue *float64 `db:"min_value"`
+		MaxValue *float64 `db:"max_value"`
+		Version  int      `db:"version"`
+	}
+	err := tx.Get(&old, "SELECT min_value, max_value, version FROM thresholds WHERE type = $1 FOR UPDATE", t.Type)
+	if err != nil && err != sql.ErrNoRows {
+		return saved, err
+	}
+
+	// Для еще не созданного порога текущая версия - 0
+	if change.IfVersion != nil && *change.IfVersion != old.Version {
+		conflict := &VersionConflictError{Current: models.Threshold{Type: t.Type}}
+		if err == nil {
+			if err := tx.Get(&conflict.Current, "SELECT * FROM thresholds WHERE type = $1", t.Type); err != nil {
+				return saved, err
+			}
 		}
+		return saved, conflict
 	}
-	return nil
+
+	if err := tx.Get(&saved, `
+        INSERT INTO thresholds (type, min_value, max_value)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (type) DO UPDATE SET 
+            min_value = EXCLUDED.min_value, 
+            max_value = EXCLUDED.max_value,
+            version = thresholds.version + 1,
+            updated_at = CURRENT_TIMESTAMP
+        RETURNING *`,
+		t.Type, t.MinValue, t.MaxValue); err != nil {
+		return saved, err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO threshold_history
+            (type, old_min_value, old_max_value, new_min_value, new_max_value, changed_by, reason, rollback_of)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		t.Type, old.MinValue, old.MaxValue, saved.MinValue, saved.MaxValue,
+		change.User, change.Reason, change.RollbackOf)
+	return saved, err
 }
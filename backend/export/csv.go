@@ -0,0 +1,66 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Формат времени в CSV: Excel распознает его как дату
+const csvTimeLayout = "2006-01-02 15:04:05"
+
+type csvWriter struct {
+	w    *csv.Writer
+	opts Options
+	row  []string
+}
+
+func newCSVWriter(w io.Writer, opts Options) *csvWriter {
+	cw := csv.NewWriter(w)
+	if opts.Decimal == ',' {
+		cw.Comma = ';'
+	}
+	return &csvWriter{w: cw, opts: opts}
+}
+
+func (c *csvWriter) Header(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Row(values []interface{}) error {
+	c.row = c.row[:0]
+	for _, v := range values {
+		c.row = append(c.row, c.format(v))
+	}
+	if err := c.w.Write(c.row); err != nil {
+		return err
+	}
+	// Сбрасываем буфер построчно, чтобы клиент получал данные сразу
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatFloat(v, c.opts.Decimal)
+	case time.Time:
+		return v.In(c.opts.Location).Format(csvTimeLayout)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,81 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые форматы выгрузки
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+var Formats = []string{FormatCSV, FormatNDJSON, FormatXLSX}
+
+// Writer пишет таблицу построчно, не накапливая строки в памяти.
+// Значения ячеек: string, int, int64, float64, time.Time или nil (пустая ячейка).
+type Writer interface {
+	Header(columns []string) error
+	Row(values []interface{}) error
+	// Close дописывает окончание файла; поток w при этом не закрывается
+	Close() error
+}
+
+// Options - параметры оформления значений
+type Options struct {
+	// Часовой пояс для времени; по умолчанию UTC
+	Location *time.Location
+	// Десятичный разделитель в CSV: '.' или ','. При ',' поля разделяются ';',
+	// как ожидает Excel в русской локали.
+	Decimal rune
+	// Наибольшее число строк на листе XLSX вместе с заголовком; дальше
+	// начинается новый лист. 0 - предел Excel, XLSXMaxRows.
+	SheetRows int
+}
+
+// New создает Writer для формата format
+func New(format string, w io.Writer, opts Options) (Writer, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.Decimal == 0 {
+		opts.Decimal = '.'
+	}
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, opts), nil
+	case FormatNDJSON:
+		return newNDJSONWriter(w, opts), nil
+	case FormatXLSX:
+		return newXLSXWriter(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// ContentType возвращает MIME тип формата
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+func formatFloat(v float64, decimal rune) string {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if decimal != '.' {
+		s = strings.Replace(s, ".", string(decimal), 1)
+	}
+	return s
+}
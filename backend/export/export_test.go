@@ -0,0 +1,118 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"realtime-app/export"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	columns = []string{"timestamp", "type", "equipment_id", "value"}
+	ts      = time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	rows    = [][]interface{}{
+		{ts, "temperature", 2, 25.5},
+		{ts.Add(time.Second), "temperature", nil, 1013.25},
+	}
+)
+
+func write(t *testing.T, format string, opts export.Options) []byte {
+	var buf bytes.Buffer
+	w, err := export.New(format, &buf, opts)
+	if err != nil {
+		t.Fatalf("Error creating writer: %v", err)
+	}
+	assert.NoError(t, w.Header(columns))
+	for _, row := range rows {
+		assert.NoError(t, w.Row(row))
+	}
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestCSVDecimalCommaAndTimezone(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skipf("Time zone data unavailable: %v", err)
+	}
+
+	out := write(t, export.FormatCSV, export.Options{Location: moscow, Decimal: ','})
+
+	assert.Equal(t, "timestamp;type;equipment_id;value\n"+
+		"2024-03-01 12:30:00;temperature;2;25,5\n"+
+		"2024-03-01 12:30:01;temperature;;1013,25\n", string(out))
+}
+
+func TestNDJSONKeepsColumnOrder(t *testing.T) {
+	out := write(t, export.FormatNDJSON, export.Options{})
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+	assert.Equal(t, `{"timestamp":"2024-03-01T09:30:00Z","type":"temperature","equipment_id":2,"value":25.5}`, lines[0])
+	assert.Equal(t, `{"timestamp":"2024-03-01T09:30:01Z","type":"temperature","equipment_id":null,"value":1013.25}`, lines[1])
+}
+
+// readWorkbook распаковывает книгу и проверяет, что каждая часть - корректный XML
+func readWorkbook(t *testing.T, out []byte) map[string]string {
+	archive, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("Error reading workbook: %v", err)
+	}
+
+	parts := map[string]string{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Error opening %s: %v", f.Name, err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		assert.NoError(t, err)
+		parts[f.Name] = string(body)
+
+		dec := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			if _, err := dec.Token(); err != nil {
+				assert.ErrorIs(t, err, io.EOF, f.Name)
+				break
+			}
+		}
+	}
+	return parts
+}
+
+func TestXLSXIsValidWorkbook(t *testing.T) {
+	parts := readWorkbook(t, write(t, export.FormatXLSX, export.Options{}))
+
+	sheet, ok := parts["xl/worksheets/sheet1.xml"]
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Contains(t, parts, "[Content_Types].xml")
+	assert.Contains(t, sheet, `<c r="B1" t="inlineStr"><is><t>type</t></is></c>`)
+	// 2024-03-01 09:30 - 45352-й день от начала отсчета Excel
+	assert.Contains(t, sheet, `<c r="A2" s="1"><v>45352.395833333336</v></c>`)
+	assert.Contains(t, sheet, `<c r="D3"><v>1013.25</v></c>`)
+	assert.NotContains(t, sheet, `r="C3"`)
+}
+
+func TestXLSXStartsNewSheetAtRowLimit(t *testing.T) {
+	parts := readWorkbook(t, write(t, export.FormatXLSX, export.Options{SheetRows: 2}))
+
+	first, second := parts["xl/worksheets/sheet1.xml"], parts["xl/worksheets/sheet2.xml"]
+	assert.NotContains(t, first, `r="A3"`)
+	// Заголовок повторяется на новом листе
+	assert.Contains(t, second, `<c r="B1" t="inlineStr"><is><t>type</t></is></c>`)
+	assert.Contains(t, second, `<c r="D2"><v>1013.25</v></c>`)
+	assert.Contains(t, parts["xl/workbook.xml"], `<sheet name="sensor_data_2" sheetId="2" r:id="rId2"/>`)
+	assert.Contains(t, parts["xl/_rels/workbook.xml.rels"], `Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"`)
+	assert.Contains(t, parts["[Content_Types].xml"], `/xl/worksheets/sheet2.xml`)
+}
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ndjsonWriter пишет по объекту JSON на строку; ключи - имена колонок
+// в заданном порядке
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	opts    Options
+	columns [][]byte
+}
+
+func newNDJSONWriter(w io.Writer, opts Options) *ndjsonWriter {
+	return &ndjsonWriter{w: bufio.NewWriter(w), opts: opts}
+}
+
+func (n *ndjsonWriter) Header(columns []string) error {
+	n.columns = make([][]byte, len(columns))
+	for i, c := range columns {
+		key, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		n.columns[i] = key
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) Row(values []interface{}) error {
+	n.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+		if t, ok := v.(time.Time); ok {
+			v = t.In(n.opts.Location).Format(time.RFC3339Nano)
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		n.w.Write(n.columns[i])
+		n.w.WriteByte(':')
+		n.w.Write(value)
+	}
+	n.w.WriteString("}\n")
+	return n.w.Flush()
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
@@ -0,0 +1,202 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Постоянные части книги Office Open XML. Листы пишутся в архив построчно,
+// строки - встроенными (inlineStr), без таблицы общих строк; перечень листов
+// в [Content_Types].xml и xl/workbook.xml дописывается в Close.
+var xlsxParts = []struct{ name, body string }{
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	// Стиль 1 - дата и время
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>
+<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>
+</styleSheet>`},
+}
+
+// XLSXMaxRows - наибольшее число строк на листе Excel
+const XLSXMaxRows = 1048576
+
+// Начало отсчета дат Excel
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+type xlsxWriter struct {
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	opts    Options
+	header  []interface{}
+	sheets  int
+	row     int
+	maxRows int
+}
+
+func newXLSXWriter(w io.Writer, opts Options) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		if err := writePart(zw, part.name, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	x := &xlsxWriter{zip: zw, opts: opts, maxRows: opts.SheetRows}
+	if x.maxRows <= 0 || x.maxRows > XLSXMaxRows {
+		x.maxRows = XLSXMaxRows
+	}
+	if err := x.nextSheet(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func writePart(zw *zip.Writer, name, body string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, body)
+	return err
+}
+
+// nextSheet закрывает текущий лист и начинает следующий
+func (x *xlsxWriter) nextSheet() error {
+	if x.sheet != nil {
+		if err := x.endSheet(); err != nil {
+			return err
+		}
+	}
+	x.sheets++
+	sheet, err := x.zip.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", x.sheets))
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(sheet)
+	x.row = 0
+	x.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return nil
+}
+
+func (x *xlsxWriter) endSheet() error {
+	x.sheet.WriteString(`</sheetData></worksheet>`)
+	return x.sheet.Flush()
+}
+
+func (x *xlsxWriter) Header(columns []string) error {
+	x.header = make([]interface{}, len(columns))
+	for i, c := range columns {
+		x.header[i] = c
+	}
+	return x.writeRow(x.header)
+}
+
+// Row пишет строку; на заполненном листе начинается новый, с тем же заголовком
+func (x *xlsxWriter) Row(values []interface{}) error {
+	if x.row >= x.maxRows {
+		if err := x.nextSheet(); err != nil {
+			return err
+		}
+		if x.header != nil {
+			if err := x.writeRow(x.header); err != nil {
+				return err
+			}
+		}
+	}
+	return x.writeRow(values)
+}
+
+func (x *xlsxWriter) writeRow(values []interface{}) error {
+	x.row++
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.row)
+	for i, v := range values {
+		ref := columnName(i) + strconv.Itoa(x.row)
+		switch v := v.(type) {
+		case nil:
+		case string:
+			fmt.Fprintf(x.sheet, `<c r="%s" t="inlineStr"><is><t>`, ref)
+			xml.EscapeText(x.sheet, []byte(v))
+			x.sheet.WriteString(`</t></is></c>`)
+		case int:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case int64:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case float64:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%s</v></c>`, ref, formatFloat(v, '.'))
+		case time.Time:
+			fmt.Fprintf(x.sheet, `<c r="%s" s="1"><v>%s</v></c>`, ref, formatFloat(excelSerial(v.In(x.opts.Location)), '.'))
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+
+	var types, sheets, rels strings.Builder
+	for i := 1; i <= x.sheets; i++ {
+		name := "sensor_data"
+		if i > 1 {
+			name += "_" + strconv.Itoa(i)
+		}
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", i)
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, name, i, i)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`+"\n", i, i)
+	}
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+` + types.String() + `<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>` + sheets.String() + `</sheets>
+</workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+` + rels.String() + fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, x.sheets+1) + `
+</Relationships>`},
+	}
+	for _, part := range parts {
+		if err := writePart(x.zip, part.name, part.body); err != nil {
+			return err
+		}
+	}
+	return x.zip.Close()
+}
+
+// excelSerial переводит местное время в число дней от начала отсчета Excel
+func excelSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24
+}
+
+// columnName возвращает буквенное имя колонки: 0 - A, 25 - Z, 26 - AA
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,150 @@
+package forecast
+
+import (
+	"fmt"
+	"realtime-app/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Параметры прогноза по умолчанию
+const (
+	DefaultWindow     = 2 * time.Hour
+	DefaultStep       = time.Minute
+	DefaultHorizon    = 30 * time.Minute
+	DefaultConfidence = 0.95
+)
+
+// Квантили нормального распределения для доступных уровней доверия
+var zScores = map[float64]float64{
+	0.8:  1.2816,
+	0.9:  1.6449,
+	0.95: 1.9600,
+	0.99: 2.5758,
+}
+
+// Confidences - допустимые уровни доверия
+var Confidences = []float64{0.8, 0.9, 0.95, 0.99}
+
+// Request - параметры прогноза: история за Window, усредненная по Step,
+// прогноз на Horizon вперед с тем же шагом
+type Request struct {
+	Type        string
+	EquipmentID *int
+	Method      string
+	Window      time.Duration
+	Step        time.Duration
+	Horizon     time.Duration
+	Confidence  float64
+}
+
+// Build строит прогноз по истории из sensor_data. Если задан limit,
+// в прогнозе отмечается первый ожидаемый выход за его границы.
+func Build(db *sqlx.DB, req Request, limit *models.Threshold) (models.Forecast, error) {
+	z, ok := zScores[req.Confidence]
+	if !ok {
+		return models.Forecast{}, fmt.Errorf("unsupported confidence %v", req.Confidence)
+	}
+	stepSeconds := int(req.Step / time.Second)
+
+	var history []struct {
+		Bucket time.Time `db:"bucket"`
+		Value  float64   `db:"value"`
+	}
+	if err := db.Select(&history, `
+		SELECT TIMESTAMP 'epoch' + FLOOR(EXTRACT(EPOCH FROM timestamp) / $4) * $4 * INTERVAL '1 second' AS bucket,
+		       AVG(value) AS value
+		FROM sensor_data
+		WHERE type = $1 AND timestamp >= LOCALTIMESTAMP - $2 * INTERVAL '1 second'
+		  AND ($3::int IS NULL OR id_equipment = $3)
+		GROUP BY 1
+		ORDER BY 1`,
+		req.Type, req.Window.Seconds(), req.EquipmentID, stepSeconds); err != nil {
+		return models.Forecast{}, err
+	}
+	// Последний интервал еще заполняется и искажает тренд
+	if len(history) > 0 {
+		history = history[:len(history)-1]
+	}
+	if len(history) < MinPoints {
+		return models.Forecast{}, fmt.Errorf("%w: %d intervals in the window, at least %d required", ErrNotEnoughData, len(history), MinPoints)
+	}
+
+	last := history[len(history)-1]
+	horizon := int(req.Horizon / req.Step)
+	at := make([]time.Time, horizon)
+	for h := range at {
+		at[h] = last.Bucket.Add(time.Duration(h+1) * req.Step)
+	}
+
+	var predictions []Prediction
+	var err error
+	switch req.Method {
+	case models.ForecastHolt:
+		ys := make([]float64, len(history))
+		for i, p := range history {
+			ys[i] = p.Value
+		}
+		predictions, err = Holt(ys, horizon)
+	case models.ForecastLinear:
+		origin := history[0].Bucket
+		xs, ys := make([]float64, len(history)), make([]float64, len(history))
+		for i, p := range history {
+			xs[i], ys[i] = p.Bucket.Sub(origin).Seconds(), p.Value
+		}
+		future := make([]float64, horizon)
+		for h, t := range at {
+			future[h] = t.Sub(origin).Seconds()
+		}
+		predictions, err = Linear(xs, ys, future)
+	default:
+		err = fmt.Errorf("unknown forecast method %q", req.Method)
+	}
+	if err != nil {
+		return models.Forecast{}, err
+	}
+
+	f := models.Forecast{
+		Type:        req.Type,
+		EquipmentID: req.EquipmentID,
+		Method:      req.Method,
+		StepSeconds: stepSeconds,
+		Confidence:  req.Confidence,
+		Samples:     len(history),
+		Points:      make([]models.ForecastPoint, horizon),
+	}
+	for h, p := range predictions {
+		f.Points[h] = models.ForecastPoint{
+			Timestamp: at[h],
+			Value:     p.Value,
+			Lower:     p.Value - z*p.StdErr,
+			Upper:     p.Value + z*p.StdErr,
+		}
+	}
+	if limit != nil {
+		f.Crossing = crossing(f, last.Value, *limit)
+	}
+	return f, nil
+}
+
+// crossing находит первую прогнозную точку за границами. Если показания уже
+// за границей, это не прогноз, а действующая тревога.
+func crossing(f models.Forecast, current float64, limit models.Threshold) *models.PredictedCrossing {
+	if current < limit.MinValue || current > limit.MaxValue {
+		return nil
+	}
+	for _, p := range f.Points {
+		c := &models.PredictedCrossing{Type: f.Type, EquipmentID: f.EquipmentID, PredictedValue: p.Value, At: p.Timestamp}
+		switch {
+		case p.Value > limit.MaxValue:
+			c.Direction, c.LimitValue = models.AlarmHigh, limit.MaxValue
+		case p.Value < limit.MinValue:
+			c.Direction, c.LimitValue = models.AlarmLow, limit.MinValue
+		default:
+			continue
+		}
+		return c
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNotEnoughData - истории недостаточно для прогноза
+var ErrNotEnoughData = errors.New("not enough data")
+
+// Минимальное число точек истории
+const MinPoints = 10
+
+// Prediction - прогнозное значение и его стандартная ошибка
+type Prediction struct {
+	Value  float64
+	StdErr float64
+}
+
+// Linear строит регрессию ys по xs и прогнозирует значения в точках at.
+// Ошибка учитывает и разброс вокруг прямой, и неточность ее оценки.
+func Linear(xs, ys, at []float64) ([]Prediction, error) {
+	n := float64(len(xs))
+	if len(xs) < MinPoints || len(xs) != len(ys) {
+		return nil, ErrNotEnoughData
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX, meanY = meanX/n, meanY/n
+
+	var sxx, sxy float64
+	for i := range xs {
+		sxx += (xs[i] - meanX) * (xs[i] - meanX)
+		sxy += (xs[i] - meanX) * (ys[i] - meanY)
+	}
+	if sxx == 0 {
+		return nil, ErrNotEnoughData
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for i := range xs {
+		r := ys[i] - (intercept + slope*xs[i])
+		sse += r * r
+	}
+	s := math.Sqrt(sse / (n - 2))
+
+	predictions := make([]Prediction, len(at))
+	for i, x := range at {
+		predictions[i] = Prediction{
+			Value:  intercept + slope*x,
+			StdErr: s * math.Sqrt(1+1/n+(x-meanX)*(x-meanX)/sxx),
+		}
+	}
+	return predictions, nil
+}
+
+// Holt прогнозирует равномерный ряд ys на horizon шагов вперед методом
+// Хольта. Коэффициенты сглаживания подбираются по сетке с минимальной
+// ошибкой прогноза на шаг вперед.
+func Holt(ys []float64, horizon int) ([]Prediction, error) {
+	if len(ys) < MinPoints {
+		return nil, ErrNotEnoughData
+	}
+
+	best := holtFit{sse: math.Inf(1)}
+	for a := 0.1; a < 0.95; a += 0.1 {
+		for b := 0.1; b < 0.95; b += 0.1 {
+			if fit := runHolt(ys, a, b); fit.sse < best.sse {
+				best = fit
+			}
+		}
+	}
+
+	// Дисперсия ошибки на h шагов: σ²·(1 + Σ α²(1 + jβ)²), j = 1…h-1
+	sigma := math.Sqrt(best.sse / float64(len(ys)-3))
+	predictions := make([]Prediction, horizon)
+	var spread float64
+	for h := 1; h <= horizon; h++ {
+		if h > 1 {
+			k := best.alpha * (1 + float64(h-1)*best.beta)
+			spread += k * k
+		}
+		predictions[h-1] = Prediction{
+			Value:  best.level + float64(h)*best.trend,
+			StdErr: sigma * math.Sqrt(1+spread),
+		}
+	}
+	return predictions, nil
+}
+
+type holtFit struct {
+	alpha, beta  float64
+	level, trend float64
+	sse          float64
+}
+
+func runHolt(ys []float64, alpha, beta float64) holtFit {
+	fit := holtFit{alpha: alpha, beta: beta, level: ys[0], trend: ys[1] - ys[0]}
+	for _, y := range ys[1:] {
+		e := y - (fit.level + fit.trend)
+		fit.sse += e * e
+		level := alpha*y + (1-alpha)*(fit.level+fit.trend)
+		fit.trend = beta*(level-fit.level) + (1-beta)*fit.trend
+		fit.level = level
+	}
+	return fit
+}
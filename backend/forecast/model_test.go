@@ -0,0 +1,49 @@
+package forecast_test
+
+import (
+	"math"
+	"realtime-app/forecast"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinearFollowsTrend(t *testing.T) {
+	var xs, ys []float64
+	for i := 0; i < 30; i++ {
+		x := float64(i)
+		xs = append(xs, x)
+		// Рост 0.5 за шаг с небольшим колебанием
+		ys = append(ys, 20+0.5*x+0.1*math.Sin(x))
+	}
+
+	predictions, err := forecast.Linear(xs, ys, []float64{30, 60})
+	assert.NoError(t, err)
+	if assert.Len(t, predictions, 2) {
+		assert.InDelta(t, 35.0, predictions[0].Value, 0.1)
+		assert.InDelta(t, 50.0, predictions[1].Value, 0.2)
+		// Чем дальше от окна, тем шире интервал
+		assert.Greater(t, predictions[1].StdErr, predictions[0].StdErr)
+	}
+
+	_, err = forecast.Linear(xs[:5], ys[:5], []float64{6})
+	assert.ErrorIs(t, err, forecast.ErrNotEnoughData)
+}
+
+func TestHoltFollowsTrend(t *testing.T) {
+	var ys []float64
+	for i := 0; i < 60; i++ {
+		ys = append(ys, 1000-0.2*float64(i)+0.05*math.Cos(float64(i)))
+	}
+
+	predictions, err := forecast.Holt(ys, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, predictions, 10) {
+		assert.InDelta(t, 1000-0.2*60, predictions[0].Value, 0.2)
+		assert.InDelta(t, 1000-0.2*69, predictions[9].Value, 0.5)
+		assert.Greater(t, predictions[9].StdErr, predictions[0].StdErr)
+	}
+
+	_, err = forecast.Holt(ys[:3], 10)
+	assert.ErrorIs(t, err, forecast.ErrNotEnoughData)
+}
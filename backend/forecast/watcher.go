@@ -0,0 +1,91 @@
+package forecast
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"realtime-app/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PredictionAlarms поднимает и снимает тревоги по прогнозу (alarms.Manager)
+type PredictionAlarms interface {
+	RaisePredicted(c models.PredictedCrossing) error
+	ClearPredicted(sensorType string) error
+}
+
+// Watcher периодически строит прогноз по каждому датчику относительно
+// границы оборудования, на котором он установлен (equipmentFor), и поднимает
+// тревогу, если выход за нее ожидается в пределах lead. Тревога снимается,
+// когда прогноз вернулся в пределы границ.
+type Watcher struct {
+	db           *sqlx.DB
+	limits       func() []models.EffectiveLimit
+	equipmentFor func(sensorType string) *int
+	lead         time.Duration
+	alarms       PredictionAlarms
+}
+
+func NewWatcher(db *sqlx.DB, limits func() []models.EffectiveLimit, equipmentFor func(sensorType string) *int,
+	lead time.Duration, alarms PredictionAlarms) *Watcher {
+	return &Watcher{db: db, limits: limits, equipmentFor: equipmentFor, lead: lead, alarms: alarms}
+}
+
+// Run проверяет прогнозы с заданным интервалом
+func (w *Watcher) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Tick(); err != nil {
+			log.Printf("Forecast watcher error: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// Tick проверяет прогноз по всем датчикам. Прогноз без данных тревогу не меняет.
+func (w *Watcher) Tick() error {
+	for _, l := range w.limits() {
+		// Тревога по прогнозу одна на датчик: границы другого оборудования
+		// к его показаниям не относятся
+		if !sameEquipment(l.EquipmentID, w.equipmentFor(l.Type)) {
+			continue
+		}
+
+		f, err := Build(w.db, Request{
+			Type:        l.Type,
+			EquipmentID: l.EquipmentID,
+			Method:      models.ForecastHolt,
+			Window:      DefaultWindow,
+			Step:        DefaultStep,
+			Horizon:     w.lead,
+			Confidence:  DefaultConfidence,
+		}, &models.Threshold{Type: l.Type, MinValue: l.MinValue, MaxValue: l.MaxValue})
+		if errors.Is(err, ErrNotEnoughData) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("forecast %s: %v", l.Type, err)
+		}
+
+		if f.Crossing == nil {
+			err = w.alarms.ClearPredicted(l.Type)
+		} else {
+			err = w.alarms.RaisePredicted(*f.Crossing)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameEquipment(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
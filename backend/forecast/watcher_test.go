@@ -0,0 +1,67 @@
+package forecast_test
+
+import (
+	"realtime-app/forecast"
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedAlarms struct {
+	raised  []models.PredictedCrossing
+	cleared []string
+}
+
+func (a *recordedAlarms) RaisePredicted(c models.PredictedCrossing) error {
+	a.raised = append(a.raised, c)
+	return nil
+}
+
+func (a *recordedAlarms) ClearPredicted(sensorType string) error {
+	a.cleared = append(a.cleared, sensorType)
+	return nil
+}
+
+// Граница оборудования, на котором датчика нет, не заслоняет границу
+// оборудования, на котором он установлен, даже если идет в списке раньше
+func TestWatcherUsesBoundEquipmentLimit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	spare, bound := 1, 2
+	limits := func() []models.EffectiveLimit {
+		return []models.EffectiveLimit{
+			{Type: "temperature", EquipmentID: &spare, MinValue: 0, MaxValue: 200, Source: models.LimitSourceEquipment},
+			{Type: "temperature", EquipmentID: &bound, MinValue: 20, MaxValue: 50, Source: models.LimitSourceEquipment},
+		}
+	}
+	equipmentFor := func(string) *int { return &bound }
+	alarms := &recordedAlarms{}
+	watcher := forecast.NewWatcher(sqlx.NewDb(db, "sqlmock"), limits, equipmentFor, 30*time.Minute, alarms)
+
+	// Температура растет на 0.5 в минуту и через полчаса превысит 50
+	rows := sqlmock.NewRows([]string{"bucket", "value"})
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		rows.AddRow(start.Add(time.Duration(i)*time.Minute), 30+0.5*float64(i))
+	}
+	mock.ExpectQuery("FROM sensor_data").
+		WithArgs("temperature", sqlmock.AnyArg(), bound, sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	assert.NoError(t, watcher.Tick())
+
+	if assert.Len(t, alarms.raised, 1) {
+		assert.Equal(t, &bound, alarms.raised[0].EquipmentID)
+		assert.Equal(t, 50.0, alarms.raised[0].LimitValue)
+	}
+	assert.Empty(t, alarms.cleared)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
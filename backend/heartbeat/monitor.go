@@ -0,0 +1,223 @@
+package heartbeat
+
+import (
+	"fmt"
+	"log"
+	"realtime-app/models"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Окно, в котором при запуске ищутся последние показания датчиков.
+// Датчики, молчащие дольше, не отслеживаются до первого нового показания.
+const LoadWindow = 24 * time.Hour
+
+// Handler вызывается на каждую потерю и восстановление связи
+type Handler func(models.CommunicationEvent)
+
+// Monitor запоминает время последнего показания каждого датчика и поднимает
+// потерю связи, если датчик молчит дольше ожидаемого периода из
+// sensor_intervals (или defaultInterval). Потери хранятся в communication_losses.
+type Monitor struct {
+	db              *sqlx.DB
+	defaultInterval time.Duration
+
+	mu       sync.Mutex
+	last     map[string]models.SensorData // последнее показание по типу датчика
+	expected map[string]time.Duration
+	lost     map[string]models.CommunicationLoss
+	handlers []Handler
+}
+
+func NewMonitor(db *sqlx.DB, defaultInterval time.Duration) *Monitor {
+	return &Monitor{
+		db:              db,
+		defaultInterval: defaultInterval,
+		last:            make(map[string]models.SensorData),
+		expected:        make(map[string]time.Duration),
+		lost:            make(map[string]models.CommunicationLoss),
+	}
+}
+
+// OnEvent подписывает обработчик на события связи
+func (m *Monitor) OnEvent(handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Load восстанавливает последние показания и незакрытые потери связи после перезапуска
+func (m *Monitor) Load() error {
+	var readings []models.SensorData
+	if err := m.db.Select(&readings, `
+		SELECT DISTINCT ON (type) * FROM sensor_data
+		WHERE timestamp >= LOCALTIMESTAMP - $1 * INTERVAL '1 second'
+		ORDER BY type, timestamp DESC, id DESC`,
+		LoadWindow.Seconds()); err != nil {
+		return err
+	}
+	var losses []models.CommunicationLoss
+	if err := m.db.Select(&losses, "SELECT * FROM communication_losses WHERE restored_at IS NULL"); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	for _, r := range readings {
+		m.last[r.Type] = r
+	}
+	for _, l := range losses {
+		m.lost[l.Type] = l
+		if _, ok := m.last[l.Type]; !ok {
+			m.last[l.Type] = models.SensorData{Type: l.Type, EquipmentID: l.EquipmentID, Timestamp: l.LastReadingAt}
+		}
+	}
+	m.mu.Unlock()
+
+	return m.loadIntervals()
+}
+
+// loadIntervals перечитывает ожидаемые периоды датчиков
+func (m *Monitor) loadIntervals() error {
+	var intervals []models.SensorInterval
+	if err := m.db.Select(&intervals, "SELECT * FROM sensor_intervals"); err != nil {
+		return err
+	}
+
+	expected := make(map[string]time.Duration, len(intervals))
+	for _, i := range intervals {
+		expected[i.Type] = time.Duration(i.ExpectedSeconds) * time.Second
+	}
+	m.mu.Lock()
+	m.expected = expected
+	m.mu.Unlock()
+	return nil
+}
+
+// Expected возвращает ожидаемый период показаний датчика
+func (m *Monitor) Expected(sensorType string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.expectedLocked(sensorType)
+}
+
+func (m *Monitor) expectedLocked(sensorType string) time.Duration {
+	if d, ok := m.expected[sensorType]; ok {
+		return d
+	}
+	return m.defaultInterval
+}
+
+// Observe отмечает показание и закрывает потерю связи с датчиком, если она была
+func (m *Monitor) Observe(reading models.SensorData) error {
+	m.mu.Lock()
+	m.last[reading.Type] = reading
+	loss, wasLost := m.lost[reading.Type]
+	delete(m.lost, reading.Type)
+	handlers := m.handlers
+	m.mu.Unlock()
+	if !wasLost {
+		return nil
+	}
+
+	if err := m.db.Get(&loss,
+		"UPDATE communication_losses SET restored_at = $2 WHERE id = $1 RETURNING *",
+		loss.ID, reading.Timestamp); err != nil {
+		return err
+	}
+	emit(handlers, models.CommunicationEvent{Event: models.CommunicationEventRestored, Loss: loss, Time: reading.Timestamp})
+	return nil
+}
+
+// Run проверяет датчики с заданным интервалом
+func (m *Monitor) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := m.Tick(); err != nil {
+			log.Printf("Sensor heartbeat check error: %v", err)
+		}
+	}
+}
+
+// Tick перечитывает ожидаемые периоды и проверяет датчики на текущий момент
+func (m *Monitor) Tick() error {
+	if err := m.loadIntervals(); err != nil {
+		return err
+	}
+	return m.Check(time.Now())
+}
+
+// Check поднимает потерю связи с датчиками, молчащими к моменту now дольше ожидаемого
+func (m *Monitor) Check(now time.Time) error {
+	m.mu.Lock()
+	var silent []models.SensorData
+	for t, r := range m.last {
+		if _, ok := m.lost[t]; !ok && now.Sub(r.Timestamp) > m.expectedLocked(t) {
+			silent = append(silent, r)
+		}
+	}
+	handlers := m.handlers
+	m.mu.Unlock()
+	sort.Slice(silent, func(i, j int) bool { return silent[i].Type < silent[j].Type })
+
+	for _, r := range silent {
+		var loss models.CommunicationLoss
+		if err := m.db.Get(&loss, `
+			INSERT INTO communication_losses (type, equipment_id, last_reading_at, detected_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *`,
+			r.Type, r.EquipmentID, r.Timestamp, now.UTC()); err != nil {
+			return fmt.Errorf("communication loss %s: %v", r.Type, err)
+		}
+
+		m.mu.Lock()
+		m.lost[r.Type] = loss
+		latest := m.last[r.Type]
+		m.mu.Unlock()
+		emit(handlers, models.CommunicationEvent{Event: models.CommunicationEventLost, Loss: loss, Time: now})
+
+		// Показание могло прийти, пока шла запись потери
+		if latest.Timestamp.After(r.Timestamp) {
+			if err := m.Observe(latest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Status возвращает состояние связи со всеми отслеживаемыми датчиками
+func (m *Monitor) Status() []models.SensorStatus {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]models.SensorStatus, 0, len(m.last))
+	for t, r := range m.last {
+		s := models.SensorStatus{
+			Type:            t,
+			EquipmentID:     r.EquipmentID,
+			LastReadingAt:   r.Timestamp,
+			SilentSeconds:   now.Sub(r.Timestamp).Seconds(),
+			ExpectedSeconds: int(m.expectedLocked(t) / time.Second),
+			State:           models.CommunicationOK,
+		}
+		if loss, ok := m.lost[t]; ok {
+			s.State = models.CommunicationLost
+			s.Loss = &loss
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Type < statuses[j].Type })
+	return statuses
+}
+
+func emit(handlers []Handler, e models.CommunicationEvent) {
+	for _, h := range handlers {
+		h(e)
+	}
+}
@@ -0,0 +1,79 @@
+package heartbeat_test
+
+import (
+	"realtime-app/heartbeat"
+	"realtime-app/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var lossColumns = []string{"id", "type", "equipment_id", "last_reading_at", "detected_at", "restored_at"}
+
+func TestCheckRaisesAndObserveRestoresCommunication(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	monitor := heartbeat.NewMonitor(sqlx.NewDb(db, "sqlmock"), 10*time.Second)
+	var events []models.CommunicationEvent
+	monitor.OnEvent(func(e models.CommunicationEvent) { events = append(events, e) })
+
+	assert.NoError(t, monitor.Observe(models.SensorData{ID: 1, Type: "temperature", Value: 20, Timestamp: start}))
+
+	// В пределах ожидаемого периода связь есть
+	assert.NoError(t, monitor.Check(start.Add(10*time.Second)))
+	assert.Empty(t, events)
+
+	detected := start.Add(11 * time.Second)
+	mock.ExpectQuery("INSERT INTO communication_losses").
+		WithArgs("temperature", nil, start, detected).
+		WillReturnRows(sqlmock.NewRows(lossColumns).AddRow(1, "temperature", nil, start, detected, nil))
+	assert.NoError(t, monitor.Check(detected))
+	// Потеря поднимается один раз
+	assert.NoError(t, monitor.Check(detected.Add(time.Minute)))
+
+	if assert.Len(t, monitor.Status(), 1) {
+		assert.Equal(t, models.CommunicationLost, monitor.Status()[0].State)
+	}
+
+	restored := start.Add(2 * time.Minute)
+	mock.ExpectQuery("UPDATE communication_losses SET restored_at").
+		WithArgs(1, restored).
+		WillReturnRows(sqlmock.NewRows(lossColumns).AddRow(1, "temperature", nil, start, detected, restored))
+	assert.NoError(t, monitor.Observe(models.SensorData{ID: 2, Type: "temperature", Value: 21, Timestamp: restored}))
+
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.CommunicationEventLost, events[0].Event)
+		assert.Equal(t, models.CommunicationEventRestored, events[1].Event)
+		assert.Equal(t, restored, *events[1].Loss.RestoredAt)
+	}
+	assert.Equal(t, models.CommunicationOK, monitor.Status()[0].State)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTickUsesExpectedInterval(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	monitor := heartbeat.NewMonitor(sqlx.NewDb(db, "sqlmock"), 10*time.Second)
+	assert.NoError(t, monitor.Observe(models.SensorData{Type: "pressure", Timestamp: time.Now().Add(-time.Minute)}))
+
+	// Датчик присылает показания раз в 5 минут, минута тишины - норма
+	mock.ExpectQuery("SELECT \\* FROM sensor_intervals").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "expected_seconds"}).AddRow("pressure", 300))
+	assert.NoError(t, monitor.Tick())
+
+	assert.Equal(t, 5*time.Minute, monitor.Expected("pressure"))
+	assert.Equal(t, 10*time.Second, monitor.Expected("humidity"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -5,9 +5,23 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
+	"os"
+	"realtime-app/alarms"
+	"realtime-app/anomaly"
 	"realtime-app/api"
 	"realtime-app/db"
+	"realtime-app/forecast"
+	"realtime-app/heartbeat"
 	"realtime-app/models"
+	"realtime-app/notify"
+	"realtime-app/profiles"
+	"realtime-app/recent"
+	"realtime-app/retention"
+	"realtime-app/rollup"
+	"realtime-app/store"
+	"realtime-app/stream"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -17,6 +31,40 @@ import (
 
 const timeRefresh time.Duration = 1 * time.Second
 
+// Период обновления откладывания, подавления и эскалации тревог
+const alarmRefreshInterval = 10 * time.Second
+
+// Период проверки расписания профилей порогов
+const profileCheckInterval = 30 * time.Second
+
+// Период дописывания сверток показаний
+const rollupInterval = 30 * time.Second
+
+// Период создания секций и удаления устаревших показаний
+const retentionInterval = time.Hour
+
+// Окно истории для начальных баз детектора аномалий
+const anomalyWarmupWindow = 7 * 24 * time.Hour
+
+// Период проверки прогноза выхода за границы
+const forecastCheckInterval = time.Minute
+
+// Период проверки связи с датчиками и ожидаемый период показаний
+// для датчиков без записи в sensor_intervals
+const (
+	heartbeatCheckInterval  = 5 * time.Second
+	defaultExpectedInterval = 10 * timeRefresh
+)
+
+// Последние показания в памяти: час при показании в секунду на датчик
+const (
+	recentBufferSize   = 3600
+	recentWarmupWindow = time.Hour
+)
+
+// Число последних показаний каждого датчика в сообщении живого потока
+const liveReadings = 10
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -25,14 +73,21 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-// Глобальная переменная для хранения текущих порогов
-var currentThresholds = map[string]models.Threshold{
-	"temperature": {Type: "temperature", MinValue: 20, MaxValue: 35}, // Дефолтные значения
-	"humidity":    {Type: "humidity", MinValue: 30, MaxValue: 80},
-	"pressure":    {Type: "pressure", MinValue: 900, MaxValue: 1100},
-}
+// Текущие пороги, общие для генератора, HTTP обработчиков и тревог
+var thresholdStore = store.NewThresholdStore(db.DefaultThresholds)
+
+// Живой поток данных для всех WebSocket клиентов
+var hub = stream.NewHub()
 
 func main() {
+	// Служебные команды (export, import) выполняются без запуска сервера
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Подключение к PostgreSQL
 	dbConn, err := connectDB()
 	if err != nil {
@@ -46,34 +101,191 @@ func main() {
 	}
 
 	// Загрузка порогов из БД при старте
-	if err := loadThresholds(dbConn); err != nil {
+	if err := thresholdStore.Load(dbConn); err != nil {
 		log.Printf("Warning: couldn't load thresholds: %v", err)
 	}
+	if err := thresholdStore.LoadLimits(dbConn); err != nil {
+		log.Printf("Warning: couldn't load equipment limits: %v", err)
+	}
+	go publishThresholdChanges()
+
+	// Синхронизация порогов между экземплярами через LISTEN/NOTIFY
+	go func() {
+		if err := thresholdStore.Listen(dbConnString(), dbConn); err != nil {
+			log.Printf("Warning: thresholds listener stopped: %v", err)
+		}
+	}()
+
+	// Тревоги и оповещения
+	alarmManager := alarms.NewManager(dbConn, thresholdStore)
+	if err := alarmManager.Load(); err != nil {
+		log.Printf("Warning: couldn't load active alarms: %v", err)
+	}
+	dispatcher, err := newDispatcher(dbConn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	alarmManager.OnEvent(dispatcher.HandleAlarmEvent)
+	alarmManager.OnEvent(func(event models.AlarmEvent) {
+		hub.Publish(map[string]interface{}{"alarm_event": event})
+	})
+	go alarmManager.Run(alarmRefreshInterval)
+
+	// Переключение профилей порогов по расписанию
+	scheduler := profiles.NewScheduler(dbConn, updateThresholdCallback, func(sw models.ProfileSwitch) {
+		log.Printf("Threshold profile switched to %q", sw.ProfileName)
+		hub.Publish(map[string]interface{}{"profile_switch": sw})
+	})
+	go scheduler.Run(profileCheckInterval)
+
+	// Свертки показаний для агрегации за длинные периоды
+	go rollup.NewRoller(dbConn).Run(rollupInterval)
+
+	// Секции sensor_data и сроки хранения показаний
+	policy, err := retentionPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+	go retention.NewJob(dbConn, policy).Run(retentionInterval)
+
+	// Обнаружение аномалий в живом потоке
+	anomalyMonitor := anomaly.NewMonitor(dbConn, anomaly.NewDetector())
+	if err := anomalyMonitor.Warm(anomalyWarmupWindow); err != nil {
+		log.Printf("Warning: couldn't warm up anomaly detector: %v", err)
+	}
+	anomalyMonitor.OnAnomaly(func(a models.Anomaly) {
+		hub.Publish(map[string]interface{}{"anomaly": a})
+	})
+
+	// Тревога по прогнозу выхода за границы: за сколько минут до выхода
+	// ее поднимать, задает FORECAST_WARNING_MINUTES (0 - не поднимать).
+	// Тревога проходит через alarmManager, как и тревоги по показаниям:
+	// журнал, оповещения, эскалация и живой поток.
+	warningMinutes, err := strconv.Atoi(getEnv("FORECAST_WARNING_MINUTES", strconv.Itoa(int(forecast.DefaultHorizon/time.Minute))))
+	if err != nil || warningMinutes < 0 {
+		log.Fatal("invalid FORECAST_WARNING_MINUTES: must be a non-negative number of minutes")
+	}
+	if warningMinutes > 0 {
+		watcher := forecast.NewWatcher(dbConn, thresholdStore.EffectiveLimits, thresholdStore.EquipmentFor, time.Duration(warningMinutes)*time.Minute, alarmManager)
+		go watcher.Run(forecastCheckInterval)
+	}
+
+	// Потеря связи с датчиками, которые перестали присылать показания
+	heartbeatMonitor := heartbeat.NewMonitor(dbConn, defaultExpectedInterval)
+	if err := heartbeatMonitor.Load(); err != nil {
+		log.Printf("Warning: couldn't load sensor heartbeats: %v", err)
+	}
+	heartbeatMonitor.OnEvent(func(e models.CommunicationEvent) {
+		log.Printf("Sensor %s: %s", e.Loss.Type, e.Event)
+		hub.Publish(map[string]interface{}{"communication_event": e})
+	})
+	go heartbeatMonitor.Run(heartbeatCheckInterval)
+
+	// Последние показания для живого потока без запросов к БД
+	recentBuffer := recent.NewBuffer(recentBufferSize)
+	if err := recentBuffer.Warm(dbConn, recentWarmupWindow); err != nil {
+		log.Printf("Warning: couldn't warm up recent readings: %v", err)
+	}
+
+	// Буфер видит только показания своего экземпляра, поэтому выборки
+	// /api/sensor-data отдаются из него, только если SINGLE_WRITER=true -
+	// показания пишет один этот экземпляр
+	singleWriter, err := strconv.ParseBool(getEnv("SINGLE_WRITER", "false"))
+	if err != nil {
+		log.Fatal("invalid SINGLE_WRITER: must be true or false")
+	}
+	var recentReads api.RecentReadings
+	if singleWriter {
+		recentReads = recentBuffer
+	}
+
+	// Генерация данных идет независимо от подключенных клиентов,
+	// чтобы тревоги поднимались, даже когда панель никто не смотрит
+	go runGenerator(dbConn, alarmManager, anomalyMonitor, heartbeatMonitor, recentBuffer)
 
 	// Настройка HTTP маршрутов
-	setupRoutes(dbConn)
+	setupRoutes(dbConn, alarmManager, heartbeatMonitor, recentReads, policy)
 
 	// Запуск сервера
 	log.Println("Server starting on :8080...")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
 
-// Загрузка порогов из БД
-func loadThresholds(db *sqlx.DB) error {
-	var thresholds []models.Threshold
-	if err := db.Select(&thresholds, "SELECT * FROM thresholds"); err != nil {
-		return err
+// Рассылка изменений порогов в живой поток сразу, не дожидаясь следующего тика
+func publishThresholdChanges() {
+	changes, unsubscribe := thresholdStore.Subscribe()
+	defer unsubscribe()
+
+	for range changes {
+		hub.Publish(map[string]interface{}{
+			"thresholds": thresholdStore.Snapshot(),
+			"limits":     thresholdStore.EffectiveLimits(),
+		})
 	}
+}
 
-	for _, t := range thresholds {
-		currentThresholds[t.Type] = t
+// Настройка рассылки оповещений. Параметры SMTP берутся из окружения,
+// сами каналы (webhook, email) хранятся в таблице notification_channels
+func newDispatcher(db *sqlx.DB) (*notify.Dispatcher, error) {
+	dispatcher := notify.NewDispatcher(db)
+	dispatcher.Register(models.ChannelWebhook, notify.NewWebhookSender())
+
+	emailSender, err := notify.NewEmailSender(notify.SMTPConfig{
+		Host:         getEnv("SMTP_HOST", "localhost"),
+		Port:         getEnv("SMTP_PORT", "1025"),
+		From:         getEnv("SMTP_FROM", "alarms@realtime.local"),
+		Username:     os.Getenv("SMTP_USERNAME"),
+		Password:     os.Getenv("SMTP_PASSWORD"),
+		TemplateFile: os.Getenv("SMTP_TEMPLATE_FILE"),
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	dispatcher.Register(models.ChannelEmail, emailSender)
+
+	return dispatcher, nil
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Сроки хранения показаний в днях по уровням: RETENTION_RAW_DAYS,
+// RETENTION_1M_DAYS, RETENTION_1H_DAYS, RETENTION_1D_DAYS; 0 - хранить всегда
+func retentionPolicy() (retention.Policy, error) {
+	defaults := []struct {
+		level string
+		days  string
+	}{
+		{rollup.Raw, "30"},
+		{"1m", "90"},
+		{"1h", "730"},
+		{"1d", "0"},
+	}
+
+	policy := retention.Policy{}
+	for _, d := range defaults {
+		key := "RETENTION_" + strings.ToUpper(d.level) + "_DAYS"
+		days, err := strconv.Atoi(getEnv(key, d.days))
+		if err != nil || days < 0 {
+			return nil, fmt.Errorf("invalid %s: must be a non-negative number of days", key)
+		}
+		policy[d.level] = time.Duration(days) * 24 * time.Hour
+	}
+	return policy, nil
+}
+
+// Строка подключения к БД: DATABASE_URL или значение по умолчанию для docker-compose
+func dbConnString() string {
+	return getEnv("DATABASE_URL", "user=postgres password=postgres host=postgres port=5432 dbname=realtime sslmode=disable connect_timeout=5")
 }
 
 // Функция подключения к базе данных
 func connectDB() (*sqlx.DB, error) {
-	connStr := "user=postgres password=postgres host=postgres port=5432 dbname=realtime sslmode=disable connect_timeout=5"
+	connStr := dbConnString()
 	db, err := sqlx.Connect("postgres", connStr)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка подключения к БД: %v\nСтрока подключения: %s", err, connStr)
@@ -88,25 +300,74 @@ func connectDB() (*sqlx.DB, error) {
 }
 
 // Настройка маршрутов HTTP
-func setupRoutes(db *sqlx.DB) {
+func setupRoutes(db *sqlx.DB, alarmManager *alarms.Manager, heartbeatMonitor *heartbeat.Monitor, recentReads api.RecentReadings, policy retention.Policy) {
+	refreshAlarms := func() {
+		if err := alarmManager.Refresh(); err != nil {
+			log.Printf("Alarm conditions refresh error: %v", err)
+		}
+	}
+	reloadLimits := func() {
+		if err := thresholdStore.LoadLimits(db); err != nil {
+			log.Printf("Limits reload error: %v", err)
+		}
+	}
+
 	// WebSocket endpoint
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		wsHandler(w, r, db)
-	})
+	http.HandleFunc("/ws", wsHandler)
 
 	// API endpoints
 	http.HandleFunc("/api/thresholds", api.GetThresholds(db))
 	http.HandleFunc("/api/thresholds/update", api.UpdateThresholdWrapper(db, updateThresholdCallback))
+	http.HandleFunc("/api/thresholds/history", api.GetThresholdHistory(db))
+	http.HandleFunc("/api/thresholds/rollback", api.RollbackThreshold(db, updateThresholdCallback))
+	http.HandleFunc("/api/thresholds/suggest", api.GetThresholdSuggestion(db, thresholdStore.Effective))
+	http.HandleFunc("/api/thresholds/export", api.ExportBundle(db))
+	http.HandleFunc("/api/thresholds/import", api.ImportBundle(db, updateThresholdCallback, reloadLimits))
+	http.HandleFunc("/api/profiles", api.ThresholdProfiles(db, updateThresholdCallback))
+	http.HandleFunc("/api/profiles/schedule", api.ProfileSchedule(db))
+	http.HandleFunc("/api/profiles/active", api.GetActiveProfile(db))
+	http.HandleFunc("/api/limits", api.EquipmentLimits(db, reloadLimits))
+	http.HandleFunc("/api/limits/effective", api.GetEffectiveLimits(thresholdStore.EffectiveLimits))
+	http.HandleFunc("/api/parameters/reference", api.UpdateReferenceParameter(db))
+	http.HandleFunc("/api/sensor-data", api.GetSensorData(db, recentReads))
+	http.HandleFunc("/api/sensor-data/aggregate", api.GetSensorAggregate(db, policy))
+	http.HandleFunc("/api/sensor-data/export", api.ExportSensorData(db, policy))
+	http.HandleFunc("/api/sensor-data/stats", api.GetSensorStats(db, thresholdStore.Effective))
+	http.HandleFunc("/api/sensor-data/forecast", api.GetForecast(db, thresholdStore.Effective))
+	http.HandleFunc("/api/sensor-data/gaps", api.GetSensorGaps(db, heartbeatMonitor.Expected))
+	http.HandleFunc("/api/sensors/status", api.GetSensorStatus(heartbeatMonitor.Status))
+	http.HandleFunc("/api/sensors/intervals", api.SensorIntervals(db))
+	http.HandleFunc("/api/anomalies", api.GetAnomalies(db))
+	http.HandleFunc("/api/alarms", api.GetAlarms(db))
+	http.HandleFunc("/api/alarms/kpi", api.GetAlarmKPI(db))
+	http.HandleFunc("/api/alarms/ack", api.AcknowledgeAlarm(db, alarmManager.Annotate))
+	http.HandleFunc("/api/alarms/comments", api.AlarmComments(db, alarmManager.Annotate))
+	http.HandleFunc("/api/alarms/shelves", api.GetAlarmShelves(db))
+	http.HandleFunc("/api/alarms/shelve", api.ShelveAlarm(db, refreshAlarms))
+	http.HandleFunc("/api/alarms/unshelve", api.UnshelveAlarm(db, refreshAlarms))
+	http.HandleFunc("/api/alarms/escalations", api.GetEscalationHistory(db))
+	http.HandleFunc("/api/alarms/escalations/pending", api.GetPendingEscalations(db))
+	http.HandleFunc("/api/escalation/policies", api.EscalationPolicies(db))
+	http.HandleFunc("/api/equipment", api.GetEquipmentList(db))
+	http.HandleFunc("/api/equipment/create", api.CreateEquipment(db, reloadLimits))
+	http.HandleFunc("/api/equipment/status", api.UpdateEquipmentStatus(db, refreshAlarms))
+	http.HandleFunc("/api/templates", api.ThresholdTemplates(db, reloadLimits))
+	http.HandleFunc("/api/templates/apply", api.ApplyThresholdTemplates(db, reloadLimits))
+	http.HandleFunc("/api/notifications/channels", api.NotificationChannels(db))
+	http.HandleFunc("/api/notifications/log", api.GetNotificationLog(db))
 
 }
 
 func updateThresholdCallback(updatedThreshold models.Threshold) {
-	currentThresholds[updatedThreshold.Type] = updatedThreshold
+	if err := thresholdStore.Update(updatedThreshold); err != nil {
+		log.Printf("Threshold update rejected: %v", err)
+		return
+	}
 	log.Printf("Thresholds updated: %+v", updatedThreshold)
 }
 
 // WebSocket handler
-func wsHandler(w http.ResponseWriter, r *http.Request, db *sqlx.DB) {
+func wsHandler(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -114,65 +375,94 @@ func wsHandler(w http.ResponseWriter, r *http.Request, db *sqlx.DB) {
 	}
 	defer conn.Close()
 
-	ticker := time.NewTicker(timeRefresh)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			// Генерация данных с учетом текущих порогов
-			sensorData, err := generateSensorData(db)
-			if err != nil {
-				log.Printf("Error generating sensor data: %v", err)
-				continue
-			}
+	messages, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
 
-			// Получение текущих порогов
-			var thresholds []models.Threshold
-			for _, t := range currentThresholds {
-				thresholds = append(thresholds, t)
+	// Чтение нужно для обработки закрытия соединения клиентом
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
 			}
+		}
+	}()
 
-			// Отправка данных клиенту
-			if err := conn.WriteJSON(map[string]interface{}{
-				"data":       sensorData,
-				"thresholds": thresholds,
-			}); err != nil {
+	for {
+		select {
+		case msg := <-messages:
+			if err := conn.WriteJSON(msg); err != nil {
 				log.Printf("WebSocket write error: %v", err)
 				return
 			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// Периодическая генерация данных и рассылка их в живой поток
+func runGenerator(db *sqlx.DB, alarmManager *alarms.Manager, anomalyMonitor *anomaly.Monitor, heartbeatMonitor *heartbeat.Monitor, recentBuffer *recent.Buffer) {
+	ticker := time.NewTicker(timeRefresh)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		// Генерация данных с учетом текущих порогов
+		sensorData, err := generateSensorData(db, alarmManager, anomalyMonitor, heartbeatMonitor, recentBuffer)
+		if err != nil {
+			log.Printf("Error generating sensor data: %v", err)
+			continue
 		}
+
+		hub.Publish(map[string]interface{}{
+			"data":       sensorData,
+			"thresholds": thresholdStore.Snapshot(),
+			"limits":     thresholdStore.EffectiveLimits(),
+		})
 	}
 }
 
 // Генерация данных датчиков
-func generateSensorData(db *sqlx.DB) ([]models.SensorData, error) {
+func generateSensorData(db *sqlx.DB, alarmManager *alarms.Manager, anomalyMonitor *anomaly.Monitor, heartbeatMonitor *heartbeat.Monitor, recentBuffer *recent.Buffer) ([]models.SensorData, error) {
 	var allData []models.SensorData
 	randSrc := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	for sensorType, threshold := range currentThresholds {
+	for _, t := range thresholdStore.Snapshot() {
+		sensorType := t.Type
+		equipmentID := thresholdStore.EquipmentFor(sensorType)
+		threshold, _ := thresholdStore.Effective(sensorType, equipmentID)
+
 		// Генерация значения в диапазоне [min, max]
 		value := threshold.MinValue + randSrc.Float64()*(threshold.MaxValue-threshold.MinValue)
 
 		// Сохранение в базу данных
-		if _, err := db.Exec(
-			"INSERT INTO sensor_data (value, type) VALUES ($1, $2)",
-			value, sensorType,
+		var reading models.SensorData
+		if err := db.Get(&reading,
+			"INSERT INTO sensor_data (value, type, id_equipment) VALUES ($1, $2, $3) RETURNING *",
+			value, sensorType, equipmentID,
 		); err != nil {
 			return nil, fmt.Errorf("DB insert error: %v", err)
 		}
+		recentBuffer.Add(reading)
 
-		// Получение последних 10 записей
-		var records []models.SensorData
-		if err := db.Select(
-			&records,
-			"SELECT * FROM sensor_data WHERE type=$1 ORDER BY timestamp DESC LIMIT 10",
-			sensorType,
-		); err != nil {
-			return nil, fmt.Errorf("DB select error: %v", err)
+		// Датчик на связи
+		if err := heartbeatMonitor.Observe(reading); err != nil {
+			log.Printf("Sensor heartbeat error: %v", err)
+		}
+
+		// Проверка выхода за действующие для оборудования пороги
+		if err := alarmManager.Evaluate(reading); err != nil {
+			log.Printf("Alarm evaluation error: %v", err)
+		}
+
+		// Необычное поведение в пределах порогов
+		if err := anomalyMonitor.Process(reading); err != nil {
+			log.Printf("Anomaly detection error: %v", err)
 		}
 
-		allData = append(allData, records...)
+		// Последние записи датчика из буфера в памяти
+		allData = append(allData, recentBuffer.Latest(sensorType, liveReadings)...)
 	}
 
 	return allData, nil
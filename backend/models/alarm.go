@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// Состояния тревоги
+const (
+	AlarmStateActive  = "active"
+	AlarmStateCleared = "cleared"
+)
+
+// Направление выхода значения за порог
+const (
+	AlarmHigh = "high"
+	AlarmLow  = "low"
+)
+
+// Виды тревог
+const (
+	AlarmKindLimit     = "limit"     // показание вышло за границу
+	AlarmKindPredicted = "predicted" // прогноз выходит за границу в ближайшие минуты
+)
+
+// События жизненного цикла тревоги
+const (
+	EventAlarmRaised       = "raised"
+	EventAlarmCleared      = "cleared"
+	EventAlarmEscalated    = "escalated"
+	EventAlarmShelved      = "shelved"
+	EventAlarmSuppressed   = "suppressed"
+	EventAlarmReturned     = "returned" // возврат к оповещению после снятия с полки или подавления
+	EventAlarmAcknowledged = "acknowledged"
+	EventAlarmCommented    = "commented"
+)
+
+// Виды записей журнала оператора по тревоге
+const (
+	CommentKindAck     = "ack"
+	CommentKindComment = "comment"
+)
+
+// Статус оборудования, при котором его тревоги подавляются
+const EquipmentUnderRepair = "В ремонте"
+
+type Alarm struct {
+	ID             int        `json:"id" db:"id"`
+	Type           string     `json:"type" db:"type"`
+	Kind           string     `json:"kind" db:"kind"`
+	State          string     `json:"state" db:"state"`
+	Direction      string     `json:"direction" db:"direction"`
+	Value          float64    `json:"value" db:"value"`
+	Limit          float64    `json:"limit" db:"limit_value"`
+	RaisedAt       time.Time  `json:"raised_at" db:"raised_at"`
+	ClearedAt      *time.Time `json:"cleared_at,omitempty" db:"cleared_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	AcknowledgedBy *string    `json:"acknowledged_by,omitempty" db:"acknowledged_by"`
+	EquipmentID    *int       `json:"equipment_id,omitempty" db:"equipment_id"`
+	Shelved        bool       `json:"shelved" db:"shelved"`
+	Suppressed     bool       `json:"suppressed" db:"suppressed"`
+	EscalationTier int        `json:"escalation_tier" db:"escalation_tier"`
+	// Ожидаемое время выхода за границу для тревоги по прогнозу
+	PredictedAt *time.Time `json:"predicted_at,omitempty" db:"predicted_at"`
+}
+
+// Annunciated сообщает, нужно ли оповещать о тревоге: отложенные и
+// подавленные тревоги фиксируются, но не рассылаются
+func (a Alarm) Annunciated() bool {
+	return !a.Shelved && !a.Suppressed
+}
+
+// AlarmShelf - временное откладывание тревог по датчику оператором
+type AlarmShelf struct {
+	ID          int        `json:"id" db:"id"`
+	Type        string     `json:"type" db:"type"`
+	Reason      string     `json:"reason" db:"reason"`
+	ShelvedBy   string     `json:"shelved_by" db:"shelved_by"`
+	ShelvedAt   time.Time  `json:"shelved_at" db:"shelved_at"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	UnshelvedAt *time.Time `json:"unshelved_at,omitempty" db:"unshelved_at"`
+	UnshelvedBy *string    `json:"unshelved_by,omitempty" db:"unshelved_by"`
+}
+
+// AlarmComment - квитирование или комментарий оператора по тревоге
+type AlarmComment struct {
+	ID        int       `json:"id" db:"id"`
+	AlarmID   int       `json:"alarm_id" db:"alarm_id"`
+	Kind      string    `json:"kind" db:"kind"`
+	Author    string    `json:"author" db:"author"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// AlarmEvent - событие по тревоге, рассылаемое подписчикам и каналам оповещения
+type AlarmEvent struct {
+	Event   string        `json:"event"`
+	Alarm   Alarm         `json:"alarm"`
+	Tier    int           `json:"tier,omitempty"`    // уровень эскалации для события escalated
+	Comment *AlarmComment `json:"comment,omitempty"` // для событий acknowledged и commented
+	Time    time.Time     `json:"time"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AlarmKPI - сводка показателей тревог за период (в духе ISA-18.2)
+type AlarmKPI struct {
+	From                   time.Time          `json:"from"`
+	To                     time.Time          `json:"to"`
+	Total                  int                `json:"total"`
+	BySensor               []AlarmCount       `json:"by_sensor"`
+	ByEquipment            []AlarmCount       `json:"by_equipment"`
+	TopFrequent            []AlarmCount       `json:"top_frequent"`
+	MeanTimeToAckSeconds   *float64           `json:"mean_time_to_ack_seconds"`
+	MeanTimeToClearSeconds *float64           `json:"mean_time_to_clear_seconds"`
+	AverageRatePerHour     float64            `json:"average_rate_per_hour"`
+	PeakRatePerHour        int                `json:"peak_rate_per_hour"`
+	Hourly                 []AlarmHourlyCount `json:"hourly"`
+}
+
+// AlarmCount - количество тревог в разрезе датчика, оборудования или направления
+type AlarmCount struct {
+	Type          string  `json:"type,omitempty" db:"type"`
+	Direction     string  `json:"direction,omitempty" db:"direction"`
+	EquipmentID   *int    `json:"equipment_id,omitempty" db:"equipment_id"`
+	EquipmentName *string `json:"equipment_name,omitempty" db:"equipment_name"`
+	Count         int     `json:"count" db:"count"`
+}
+
+type AlarmHourlyCount struct {
+	Hour  time.Time `json:"hour" db:"hour"`
+	Count int       `json:"count" db:"count"`
+}
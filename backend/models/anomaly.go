@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Методы, которыми обнаружена аномалия
+const (
+	AnomalyEWMA     = "ewma"     // отклонение от скользящего среднего (EWMA, z-оценка)
+	AnomalySeasonal = "seasonal" // отклонение от обычного для этого часа суток уровня
+)
+
+// Anomaly - необычное показание в пределах или за пределами порогов.
+// Score - отклонение от ожидаемого в σ.
+type Anomaly struct {
+	ID           int       `db:"id" json:"id"`
+	Type         string    `db:"type" json:"type"`
+	EquipmentID  *int      `db:"equipment_id" json:"equipment_id,omitempty"`
+	SensorDataID *int      `db:"sensor_data_id" json:"sensor_data_id,omitempty"`
+	Value        float64   `db:"value" json:"value"`
+	Expected     float64   `db:"expected" json:"expected"`
+	StdDev       float64   `db:"stddev" json:"stddev"`
+	Score        float64   `db:"score" json:"score"`
+	Method       string    `db:"method" json:"method"`
+	DetectedAt   time.Time `db:"detected_at" json:"detected_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// EscalationPolicy - уровень эскалации: если тревога не квитирована за
+// DelaySeconds с момента появления, оповещаются каналы уровня Tier.
+// Политика без типа действует для всех датчиков.
+type EscalationPolicy struct {
+	ID           int     `json:"id" db:"id"`
+	Name         string  `json:"name" db:"name"`
+	Type         *string `json:"type,omitempty" db:"type"`
+	Tier         int     `json:"tier" db:"tier"`
+	DelaySeconds int     `json:"delay_seconds" db:"delay_seconds"`
+	Enabled      bool    `json:"enabled" db:"enabled"`
+}
+
+// AlarmEscalation - запись истории эскалации: кого и когда оповестили
+type AlarmEscalation struct {
+	ID          int       `json:"id" db:"id"`
+	AlarmID     int       `json:"alarm_id" db:"alarm_id"`
+	PolicyID    *int      `json:"policy_id,omitempty" db:"policy_id"`
+	Tier        int       `json:"tier" db:"tier"`
+	Recipients  string    `json:"recipients" db:"recipients"`
+	EscalatedAt time.Time `json:"escalated_at" db:"escalated_at"`
+}
+
+// PendingEscalation - ожидающая эскалация активной неквитированной тревоги
+type PendingEscalation struct {
+	AlarmID    int       `json:"alarm_id" db:"alarm_id"`
+	Type       string    `json:"type" db:"type"`
+	PolicyID   int       `json:"policy_id" db:"policy_id"`
+	PolicyName string    `json:"policy_name" db:"policy_name"`
+	Tier       int       `json:"tier" db:"tier"`
+	DueAt      time.Time `json:"due_at" db:"due_at"`
+}
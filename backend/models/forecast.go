@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Методы прогноза
+const (
+	ForecastHolt   = "holt"   // двойное экспоненциальное сглаживание (уровень и тренд)
+	ForecastLinear = "linear" // линейная регрессия по окну
+)
+
+// Forecast - прогноз показаний датчика на ближайшие минуты
+type Forecast struct {
+	Type        string          `json:"type"`
+	EquipmentID *int            `json:"equipment_id,omitempty"`
+	Method      string          `json:"method"`
+	StepSeconds int             `json:"step_seconds"`
+	Confidence  float64         `json:"confidence"`
+	Samples     int             `json:"samples"` // интервалов истории, по которым построен прогноз
+	Points      []ForecastPoint `json:"points"`
+	// Первый прогнозный выход за действующие границы; null - не ожидается
+	Crossing *PredictedCrossing `json:"crossing"`
+}
+
+// ForecastPoint - прогноз на момент Timestamp с доверительным интервалом
+type ForecastPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+	Lower     float64   `json:"lower"`
+	Upper     float64   `json:"upper"`
+}
+
+// PredictedCrossing - ожидаемый выход показаний за границу
+type PredictedCrossing struct {
+	Type           string    `json:"type"`
+	EquipmentID    *int      `json:"equipment_id,omitempty"`
+	Direction      string    `json:"direction"` // AlarmHigh или AlarmLow
+	LimitValue     float64   `json:"limit_value"`
+	PredictedValue float64   `json:"predicted_value"`
+	At             time.Time `json:"at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Виды каналов оповещения
+const (
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+type NotificationChannel struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Kind      string    `json:"kind" db:"kind"`
+	Target    string    `json:"target" db:"target"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	Tier      int       `json:"tier" db:"tier"` // 0 - дежурные, 1+ - уровни эскалации
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// NotificationLog - запись журнала доставки оповещения (одна на попытку)
+type NotificationLog struct {
+	ID        int       `json:"id" db:"id"`
+	ChannelID int       `json:"channel_id" db:"channel_id"`
+	AlarmID   int       `json:"alarm_id" db:"alarm_id"`
+	Event     string    `json:"event" db:"event"`
+	Attempt   int       `json:"attempt" db:"attempt"`
+	Status    string    `json:"status" db:"status"`
+	Error     string    `json:"error" db:"error"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -5,6 +5,8 @@ type ProcessParameter struct {
 	EquipmentID int    `db:"id_equipment" json:"equipmentId"`
 	Name        string `db:"name" json:"name"`
 	Units       string `db:"units" json:"units"`
+	// Тип датчика, показания которого соответствуют параметру
+	SensorType *string `db:"sensor_type" json:"sensorType,omitempty"`
 }
 
 type ReferenceParameter struct {
@@ -12,6 +14,8 @@ type ReferenceParameter struct {
 	ParamID int     `db:"id_param" json:"paramId"`
 	Min     float64 `db:"min_value" json:"min"`
 	Max     float64 `db:"max_value" json:"max"`
+	// Шаблон, из которого созданы границы; nil - заданы вручную
+	TemplateID *int `db:"template_id" json:"templateId,omitempty"`
 }
 
 type CurrentParameter struct {
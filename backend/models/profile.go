@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ThresholdProfile - именованный набор порогов для режима производства
+// (дневная смена, ночь, мойка, выходные)
+type ThresholdProfile struct {
+	ID          int               `json:"id" db:"id"`
+	Name        string            `json:"name" db:"name"`
+	Description string            `json:"description" db:"description"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+	Values      []ProfileValue    `json:"values" db:"-"`
+	Schedule    []ProfileSchedule `json:"schedule" db:"-"`
+}
+
+type ProfileValue struct {
+	ProfileID int     `json:"profile_id" db:"profile_id"`
+	Type      string  `json:"type" db:"type"`
+	MinValue  float64 `json:"min_value" db:"min_value"`
+	MaxValue  float64 `json:"max_value" db:"max_value"`
+}
+
+// ProfileSchedule - интервал, в который профиль должен быть активен.
+// StartTime и EndTime в формате ЧЧ:ММ по местному времени backend.
+type ProfileSchedule struct {
+	ID        int        `json:"id" db:"id"`
+	ProfileID int        `json:"profile_id" db:"profile_id"`
+	Weekdays  int        `json:"weekdays" db:"weekdays"`
+	StartTime string     `json:"start_time" db:"start_time"`
+	EndTime   string     `json:"end_time" db:"end_time"`
+	Priority  int        `json:"priority" db:"priority"`
+	ValidFrom *time.Time `json:"valid_from,omitempty" db:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to,omitempty" db:"valid_to"`
+}
+
+// ProfileSwitch - событие смены активного профиля в живом потоке
+type ProfileSwitch struct {
+	ProfileID   *int           `json:"profile_id"`
+	ProfileName string         `json:"profile_name"`
+	Thresholds  []ProfileValue `json:"thresholds"`
+	SwitchedAt  time.Time      `json:"switched_at"`
+}
@@ -11,6 +11,8 @@ type SensorData struct {
 	Value     float64   `db:"value" json:"value"`
 	Type      string    `db:"type" json:"type"`
 	Timestamp time.Time `db:"timestamp" json:"timestamp"`
+	// Оборудование, на котором установлен датчик
+	EquipmentID *int `db:"id_equipment" json:"equipment_id,omitempty"`
 }
 
 type SensorType int
@@ -22,30 +24,68 @@ const (
 	Pressure                      // 2
 )
 
+// sensorTypes - единый перечень типов датчиков: имя, параметр процесса
+// с единицами и общие пороги новой установки
+var sensorTypes = []struct {
+	name, parameter, units string
+	minValue, maxValue     float64
+}{
+	Temperature: {"temperature", "Температура", "°C", 20, 100},
+	Humidity:    {"humidity", "Влажность", "%", 30, 80},
+	Pressure:    {"pressure", "Давление", "hPa", 900, 1100},
+}
+
+// SensorTypes возвращает все типы датчиков
+func SensorTypes() []SensorType {
+	types := make([]SensorType, len(sensorTypes))
+	for i := range sensorTypes {
+		types[i] = SensorType(i)
+	}
+	return types
+}
+
+func (s SensorType) valid() bool {
+	return s >= 0 && int(s) < len(sensorTypes)
+}
+
 // String() преобразует SensorType в строку
 func (s SensorType) String() string {
-	switch s {
-	case Temperature:
-		return "temperature"
-	case Humidity:
-		return "humidity"
-	case Pressure:
-		return "pressure"
-	default:
+	if !s.valid() {
 		return "unknown"
 	}
+	return sensorTypes[s].name
+}
+
+// Parameter возвращает имя и единицы параметра процесса для типа датчика
+func (s SensorType) Parameter() (name, units string) {
+	if !s.valid() {
+		return "", ""
+	}
+	return sensorTypes[s].parameter, sensorTypes[s].units
+}
+
+// DefaultThreshold возвращает общий порог типа датчика для новой установки
+func (s SensorType) DefaultThreshold() Threshold {
+	if !s.valid() {
+		return Threshold{}
+	}
+	t := sensorTypes[s]
+	return Threshold{Type: t.name, MinValue: t.minValue, MaxValue: t.maxValue}
 }
 
 // ParseSensorType преобразует строку в SensorType
 func ParseSensorType(str string) (SensorType, error) {
-	switch str {
-	case "temperature":
-		return Temperature, nil
-	case "humidity":
-		return Humidity, nil
-	case "pressure":
-		return Pressure, nil
-	default:
-		return -1, fmt.Errorf("unknown sensor type: %s", str)
+	for i, t := range sensorTypes {
+		if t.name == str {
+			return SensorType(i), nil
+		}
 	}
+	return -1, fmt.Errorf("unknown sensor type: %s", str)
+}
+
+// SensorDataPage - страница показаний; NextCursor передается в ?cursor=
+// для получения следующей страницы, null - страниц больше нет
+type SensorDataPage struct {
+	Items      []SensorData `json:"items"`
+	NextCursor *string      `json:"next_cursor"`
 }
@@ -0,0 +1,31 @@
+package models_test
+
+import (
+	"realtime-app/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Каждый тип датчика описан: у него есть параметр процесса для шаблонов
+// границ и корректный порог по умолчанию
+func TestSensorTypesAreDescribed(t *testing.T) {
+	for _, s := range models.SensorTypes() {
+		parsed, err := models.ParseSensorType(s.String())
+		assert.NoError(t, err)
+		assert.Equal(t, s, parsed)
+
+		name, units := s.Parameter()
+		assert.NotEmpty(t, name, s.String())
+		assert.NotEmpty(t, units, s.String())
+
+		threshold := s.DefaultThreshold()
+		assert.Equal(t, s.String(), threshold.Type)
+		assert.Less(t, threshold.MinValue, threshold.MaxValue, s.String())
+	}
+
+	unknown := models.SensorType(len(models.SensorTypes()))
+	assert.Equal(t, "unknown", unknown.String())
+	_, err := models.ParseSensorType("voltage")
+	assert.Error(t, err)
+}
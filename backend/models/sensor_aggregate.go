@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// SensorAggregate - показания датчиков, сведенные в интервалы одинаковой длины
+type SensorAggregate struct {
+	From          time.Time                 `json:"from"`
+	To            time.Time                 `json:"to"`
+	BucketSeconds int                       `json:"bucket_seconds"`
+	Resolution    string                    `json:"resolution"` // raw или уровень свертки (1m, 1h, 1d)
+	Series        map[string][]SensorBucket `json:"series"`
+}
+
+// SensorBucket - сводка показаний датчика за интервал, начинающийся в Bucket
+type SensorBucket struct {
+	Type   string    `json:"-" db:"type"`
+	Bucket time.Time `json:"bucket" db:"bucket"`
+	Min    float64   `json:"min" db:"min"`
+	Max    float64   `json:"max" db:"max"`
+	Avg    float64   `json:"avg" db:"avg"`
+	Count  int       `json:"count" db:"count"`
+	First  float64   `json:"first" db:"first"`
+	Last   float64   `json:"last" db:"last"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Состояние связи с датчиком
+const (
+	CommunicationOK   = "ok"
+	CommunicationLost = "lost"
+)
+
+// События связи с датчиком
+const (
+	CommunicationEventLost     = "communication_lost"
+	CommunicationEventRestored = "communication_restored"
+)
+
+// SensorInterval - ожидаемый период поступления показаний датчика. Если
+// датчик молчит дольше, связь с ним считается потерянной.
+type SensorInterval struct {
+	Type            string `db:"type" json:"type"`
+	ExpectedSeconds int    `db:"expected_seconds" json:"expected_seconds"`
+}
+
+// CommunicationLoss - потеря связи с датчиком; RestoredAt пуст, пока
+// датчик не прислал новое показание
+type CommunicationLoss struct {
+	ID            int        `db:"id" json:"id"`
+	Type          string     `db:"type" json:"type"`
+	EquipmentID   *int       `db:"equipment_id" json:"equipment_id,omitempty"`
+	LastReadingAt time.Time  `db:"last_reading_at" json:"last_reading_at"`
+	DetectedAt    time.Time  `db:"detected_at" json:"detected_at"`
+	RestoredAt    *time.Time `db:"restored_at" json:"restored_at,omitempty"`
+}
+
+// CommunicationEvent - потеря или восстановление связи с датчиком
+type CommunicationEvent struct {
+	Event string            `json:"event"`
+	Loss  CommunicationLoss `json:"loss"`
+	Time  time.Time         `json:"time"`
+}
+
+// SensorStatus - последнее показание датчика и состояние связи с ним
+type SensorStatus struct {
+	Type            string             `json:"type"`
+	EquipmentID     *int               `json:"equipment_id,omitempty"`
+	LastReadingAt   time.Time          `json:"last_reading_at"`
+	SilentSeconds   float64            `json:"silent_seconds"`
+	ExpectedSeconds int                `json:"expected_seconds"`
+	State           string             `json:"state"`
+	Loss            *CommunicationLoss `json:"loss,omitempty"`
+}
+
+// SensorGap - промежуток без показаний датчика в sensor_data
+type SensorGap struct {
+	Type        string    `db:"type" json:"type"`
+	EquipmentID *int      `db:"id_equipment" json:"equipment_id,omitempty"`
+	Start       time.Time `db:"start" json:"start"`
+	End         time.Time `db:"end" json:"end"`
+	Seconds     float64   `db:"seconds" json:"seconds"`
+}
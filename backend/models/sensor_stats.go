@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// SensorStats - сводная статистика показаний датчика за окно.
+// При отсутствии показаний Samples = 0, остальные поля пустые.
+type SensorStats struct {
+	Type        string             `json:"type"`
+	EquipmentID *int               `json:"equipment_id,omitempty"`
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Samples     int                `json:"samples"`
+	Mean        *float64           `json:"mean"`
+	Median      *float64           `json:"median"`
+	StdDev      *float64           `json:"stddev"`
+	Min         *SensorExtreme     `json:"min"`
+	Max         *SensorExtreme     `json:"max"`
+	Percentiles []SensorPercentile `json:"percentiles"`
+	// Доля времени в пределах действующих границ; null, если границы не заданы
+	InRange *TimeInRange `json:"in_range"`
+}
+
+// SensorExtreme - экстремальное значение и момент, когда оно впервые достигнуто
+type SensorExtreme struct {
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type SensorPercentile struct {
+	P     float64 `json:"p"` // процентиль, 0…100
+	Value float64 `json:"value"`
+}
+
+// TimeInRange - доля окна, когда показания были в пределах границ. Каждое
+// показание действует до следующего; SamplesPercent - доля самих показаний.
+type TimeInRange struct {
+	MinValue       float64 `json:"min_value"`
+	MaxValue       float64 `json:"max_value"`
+	Percent        float64 `json:"percent"`
+	SamplesPercent float64 `json:"samples_percent"`
+}
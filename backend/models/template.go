@@ -0,0 +1,19 @@
+package models
+
+// ThresholdTemplate - границы датчика по умолчанию для всего оборудования
+// одного типа (Equipment.Type)
+type ThresholdTemplate struct {
+	ID            int     `json:"id" db:"id"`
+	EquipmentType string  `json:"equipment_type" db:"equipment_type"`
+	SensorType    string  `json:"sensor_type" db:"sensor_type"`
+	MinValue      float64 `json:"min_value" db:"min_value"`
+	MaxValue      float64 `json:"max_value" db:"max_value"`
+}
+
+// TemplateApplyResult - итог применения шаблонов к оборудованию
+type TemplateApplyResult struct {
+	Total     int `json:"total" db:"total"`         // границ, к которым относятся шаблоны
+	Created   int `json:"created" db:"created"`     // созданы из шаблона
+	Updated   int `json:"updated" db:"updated"`     // обновлены до значений шаблона
+	Preserved int `json:"preserved" db:"preserved"` // заданы вручную и оставлены без изменений
+}
@@ -8,6 +8,47 @@ type Threshold struct {
 	Type      string    `json:"type" db:"type"`
 	MinValue  float64   `json:"min_value" db:"min_value"`
 	MaxValue  float64   `json:"max_value" db:"max_value"`
+	Version   int       `json:"version" db:"version"` // растет на каждое изменение, служит ETag
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
+
+// ThresholdHistory - запись журнала изменений порога
+type ThresholdHistory struct {
+	ID          int       `json:"id" db:"id"`
+	Type        string    `json:"type" db:"type"`
+	OldMinValue *float64  `json:"old_min_value" db:"old_min_value"`
+	OldMaxValue *float64  `json:"old_max_value" db:"old_max_value"`
+	NewMinValue float64   `json:"new_min_value" db:"new_min_value"`
+	NewMaxValue float64   `json:"new_max_value" db:"new_max_value"`
+	ChangedBy   string    `json:"changed_by" db:"changed_by"`
+	Reason      string    `json:"reason" db:"reason"`
+	RollbackOf  *int      `json:"rollback_of,omitempty" db:"rollback_of"`
+	ChangedAt   time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// ThresholdChange - автор и причина изменения порога
+type ThresholdChange struct {
+	User       string
+	Reason     string
+	RollbackOf *int
+	// Ожидаемая текущая версия (If-Match); nil - без проверки
+	IfVersion *int
+}
+
+// Источник действующих границ
+const (
+	LimitSourceType      = "type"      // общий порог по типу датчика (thresholds)
+	LimitSourceEquipment = "equipment" // граница параметра оборудования (reference_parameters)
+	LimitSourceTemplate  = "template"  // граница оборудования из шаблона типа оборудования
+)
+
+// EffectiveLimit - действующие границы датчика на конкретном оборудовании
+type EffectiveLimit struct {
+	Type        string  `json:"type" db:"type"`
+	EquipmentID *int    `json:"equipment_id,omitempty" db:"equipment_id"`
+	ParamID     *int    `json:"param_id,omitempty" db:"param_id"`
+	MinValue    float64 `json:"min_value" db:"min_value"`
+	MaxValue    float64 `json:"max_value" db:"max_value"`
+	Source      string  `json:"source" db:"source"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Методы расчета предлагаемых порогов
+const (
+	SuggestPercentile = "percentile" // границы по перцентилям распределения
+	SuggestSigma      = "sigma"      // среднее ± k·σ
+)
+
+// ThresholdSuggestion - пороги, предложенные по истории показаний датчика
+type ThresholdSuggestion struct {
+	Type        string    `json:"type"`
+	EquipmentID *int      `json:"equipment_id,omitempty"`
+	Method      string    `json:"method"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Samples     int       `json:"samples" db:"samples"`
+	Mean        float64   `json:"mean" db:"mean"`
+	StdDev      float64   `json:"stddev" db:"stddev"`
+	MinValue    float64   `json:"min_value"`
+	MaxValue    float64   `json:"max_value"`
+	// Сколько тревог дали бы предложенные и текущие пороги на том же окне
+	Expected AlarmRate  `json:"expected"`
+	Current  *AlarmRate `json:"current,omitempty"`
+}
+
+// AlarmRate - оценка частоты тревог для пары границ на историческом окне
+type AlarmRate struct {
+	MinValue       float64 `json:"min_value"`
+	MaxValue       float64 `json:"max_value"`
+	OutsideSamples int     `json:"outside_samples" db:"outside_samples"`
+	OutsideRatio   float64 `json:"outside_ratio"`
+	// Тревога поднимается на переходе из диапазона за его границы
+	Alarms       int     `json:"alarms" db:"alarms"`
+	AlarmsPerDay float64 `json:"alarms_per_day"`
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"realtime-app/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Sender доставляет событие по тревоге на конкретный адрес канала
+type Sender interface {
+	Send(ctx context.Context, target string, event models.AlarmEvent) error
+}
+
+// Dispatcher рассылает события тревог по включенным каналам с повторными
+// попытками и ведет журнал доставки в таблице notification_log
+type Dispatcher struct {
+	db      *sqlx.DB
+	senders map[string]Sender
+
+	Retries int           // количество попыток доставки
+	Backoff time.Duration // пауза перед повтором, удваивается на каждой попытке
+	Timeout time.Duration // таймаут одной попытки
+}
+
+func NewDispatcher(db *sqlx.DB) *Dispatcher {
+	return &Dispatcher{
+		db:      db,
+		senders: make(map[string]Sender),
+		Retries: 3,
+		Backoff: 2 * time.Second,
+		Timeout: 10 * time.Second,
+	}
+}
+
+// Register задает отправителя для вида канала (webhook, email)
+func (d *Dispatcher) Register(kind string, sender Sender) {
+	d.senders[kind] = sender
+}
+
+// HandleAlarmEvent асинхронно рассылает событие, не блокируя генерацию данных.
+// События по отложенным и подавленным тревогам, а также действия операторов
+// (квитирование, комментарии) не рассылаются.
+func (d *Dispatcher) HandleAlarmEvent(event models.AlarmEvent) {
+	if !event.Alarm.Annunciated() || event.Comment != nil {
+		return
+	}
+	go d.Dispatch(event)
+}
+
+// Dispatch рассылает событие по включенным каналам и дожидается окончания доставки.
+// Эскалация уходит только каналам своего уровня, остальные события - дежурным
+// каналам и всем уровням, до которых тревога уже была эскалирована.
+func (d *Dispatcher) Dispatch(event models.AlarmEvent) {
+	fromTier, toTier := 0, event.Alarm.EscalationTier
+	if event.Event == models.EventAlarmEscalated {
+		fromTier, toTier = event.Tier, event.Tier
+	}
+
+	var channels []models.NotificationChannel
+	if err := d.db.Select(&channels, `
+		SELECT * FROM notification_channels
+		WHERE enabled AND tier BETWEEN $1 AND $2
+		ORDER BY id`,
+		fromTier, toTier); err != nil {
+		log.Printf("Notification channels load error: %v", err)
+		return
+	}
+
+	done := make(chan struct{})
+	for _, ch := range channels {
+		go func(ch models.NotificationChannel) {
+			d.Deliver(ch, event)
+			done <- struct{}{}
+		}(ch)
+	}
+	for range channels {
+		<-done
+	}
+}
+
+// Deliver отправляет событие в один канал с повторными попытками
+func (d *Dispatcher) Deliver(ch models.NotificationChannel, event models.AlarmEvent) error {
+	sender, ok := d.senders[ch.Kind]
+	if !ok {
+		log.Printf("No sender registered for channel %s (%s)", ch.Name, ch.Kind)
+		return nil
+	}
+
+	backoff := d.Backoff
+	var err error
+	for attempt := 1; attempt <= d.Retries; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), d.Timeout)
+		err = sender.Send(ctx, ch.Target, event)
+		cancel()
+
+		d.logAttempt(ch, event, attempt, err)
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("Notification to %s failed (attempt %d/%d): %v", ch.Name, attempt, d.Retries, err)
+		if attempt < d.Retries {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
+
+func (d *Dispatcher) logAttempt(ch models.NotificationChannel, event models.AlarmEvent, attempt int, sendErr error) {
+	status, errText := "sent", ""
+	if sendErr != nil {
+		status, errText = "failed", sendErr.Error()
+	}
+
+	if _, err := d.db.Exec(`
+		INSERT INTO notification_log (channel_id, alarm_id, event, attempt, status, error)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		ch.ID, event.Alarm.ID, event.Event, attempt, status, errText); err != nil {
+		log.Printf("Notification log error: %v", err)
+	}
+}
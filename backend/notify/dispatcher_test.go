@@ -0,0 +1,97 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/models"
+	"realtime-app/notify"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcherRetriesWebhook(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	// Локальный приемник webhook: первая попытка завершается ошибкой
+	var received []models.AlarmEvent
+	calls := 0
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event models.AlarmEvent
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received = append(received, event)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer sink.Close()
+
+	channel := models.NotificationChannel{ID: 7, Name: "ops", Kind: models.ChannelWebhook, Target: sink.URL, Enabled: true}
+	event := models.AlarmEvent{
+		Event: models.EventAlarmRaised,
+		Alarm: models.Alarm{ID: 3, Type: "temperature", Direction: models.AlarmHigh, Value: 40, Limit: 35},
+		Time:  time.Now(),
+	}
+
+	mock.ExpectExec("INSERT INTO notification_log").
+		WithArgs(7, 3, models.EventAlarmRaised, 1, "failed", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO notification_log").
+		WithArgs(7, 3, models.EventAlarmRaised, 2, "sent", "").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+
+	dispatcher := notify.NewDispatcher(sqlxDB)
+	dispatcher.Register(models.ChannelWebhook, notify.NewWebhookSender())
+	dispatcher.Backoff = time.Millisecond
+
+	err = dispatcher.Deliver(channel, event)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "temperature", received[0].Alarm.Type)
+		assert.Equal(t, models.EventAlarmRaised, received[0].Event)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer sink.Close()
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		mock.ExpectExec("INSERT INTO notification_log").
+			WithArgs(1, 1, models.EventAlarmCleared, attempt, "failed", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(int64(attempt), 1))
+	}
+
+	dispatcher := notify.NewDispatcher(sqlxDB)
+	dispatcher.Register(models.ChannelWebhook, notify.NewWebhookSender())
+	dispatcher.Retries = 2
+	dispatcher.Backoff = time.Millisecond
+
+	channel := models.NotificationChannel{ID: 1, Name: "ops", Kind: models.ChannelWebhook, Target: sink.URL}
+	err = dispatcher.Deliver(channel, models.AlarmEvent{Event: models.EventAlarmCleared, Alarm: models.Alarm{ID: 1}})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"realtime-app/models"
+	"strings"
+	"text/template"
+)
+
+// Шаблоны письма по умолчанию. Файл с собственными шаблонами должен
+// определять шаблоны "subject" и "body".
+const defaultEmailTemplates = `
+{{define "subject"}}[{{.Event}}] {{if .Alarm.PredictedAt}}Прогноз выхода за границу{{else}}Тревога{{end}} по датчику {{.Alarm.Type}}{{end}}
+{{define "body"}}Событие: {{.Event}}
+Датчик: {{.Alarm.Type}}
+Значение: {{printf "%.2f" .Alarm.Value}} (граница {{printf "%.2f" .Alarm.Limit}}, {{.Alarm.Direction}})
+{{- if .Alarm.PredictedAt}}
+Ожидаемый выход за границу: {{.Alarm.PredictedAt.Format "2006-01-02 15:04:05"}}
+{{- end}}
+Тревога поднята: {{.Alarm.RaisedAt.Format "2006-01-02 15:04:05"}}
+{{- if .Alarm.ClearedAt}}
+Тревога снята: {{.Alarm.ClearedAt.Format "2006-01-02 15:04:05"}}
+{{- end}}
+{{- if .Tier}}
+Уровень эскалации: {{.Tier}} (тревога не квитирована)
+{{- end}}
+Время события: {{.Time.Format "2006-01-02 15:04:05"}}
+{{end}}`
+
+type SMTPConfig struct {
+	Host         string
+	Port         string
+	From         string
+	Username     string
+	Password     string
+	TemplateFile string
+	// Корневые сертификаты для проверки сервера при STARTTLS; nil - системные
+	RootCAs *x509.CertPool
+}
+
+// EmailSender отправляет письмо по SMTP на адреса канала (через запятую)
+type EmailSender struct {
+	cfg       SMTPConfig
+	templates *template.Template
+}
+
+func NewEmailSender(cfg SMTPConfig) (*EmailSender, error) {
+	tmpl, err := template.New("email").Parse(defaultEmailTemplates)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TemplateFile != "" {
+		if tmpl, err = tmpl.ParseFiles(cfg.TemplateFile); err != nil {
+			return nil, fmt.Errorf("ошибка загрузки шаблона письма: %v", err)
+		}
+	}
+	return &EmailSender{cfg: cfg, templates: tmpl}, nil
+}
+
+func (s *EmailSender) Send(ctx context.Context, target string, event models.AlarmEvent) error {
+	var recipients []string
+	for _, addr := range strings.Split(target, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			recipients = append(recipients, addr)
+		}
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("no recipients in %q", target)
+	}
+
+	msg, err := s.render(recipients, event)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.cfg.Host, s.cfg.Port))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if s.cfg.Username != "" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host, RootCAs: s.cfg.RootCAs}); err != nil {
+				return err
+			}
+		}
+		auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// render собирает письмо с заголовками по шаблонам
+func (s *EmailSender) render(recipients []string, event models.AlarmEvent) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&subject, "subject", event); err != nil {
+		return nil, err
+	}
+	if err := s.templates.ExecuteTemplate(&body, "body", event); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", strings.TrimSpace(subject.String())))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
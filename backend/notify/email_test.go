@@ -0,0 +1,129 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"realtime-app/models"
+	"realtime-app/notify"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpCatcher - локальный SMTP сервер с STARTTLS, принимающий одно письмо
+func smtpCatcher(t *testing.T, cert tls.Certificate) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	messages := make(chan string, 1)
+
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		r, w := bufio.NewReader(conn), conn
+		reply := func(s string) { w.Write([]byte(s + "\r\n")) }
+		reply("220 localhost ESMTP")
+		secure := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				if secure {
+					reply("250-localhost\r\n250 AUTH PLAIN")
+				} else {
+					reply("250-localhost\r\n250 STARTTLS")
+				}
+			case cmd == "STARTTLS":
+				reply("220 Ready to start TLS")
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}})
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				r, w, secure = bufio.NewReader(tlsConn), tlsConn, true
+			case strings.HasPrefix(cmd, "AUTH"):
+				reply("235 Authenticated")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 Go ahead")
+				var msg strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					msg.WriteString(line)
+				}
+				messages <- msg.String()
+				reply("250 Queued")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Unknown command")
+			}
+		}
+	}()
+	return ln.Addr().String(), messages
+}
+
+func TestEmailSenderUsesSTARTTLS(t *testing.T) {
+	// Сертификат тестового HTTPS сервера выдан на 127.0.0.1
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+
+	addr, messages := smtpCatcher(t, tlsServer.TLS.Certificates[0])
+	host, port, _ := net.SplitHostPort(addr)
+
+	sender, err := notify.NewEmailSender(notify.SMTPConfig{
+		Host:     host,
+		Port:     port,
+		From:     "alarms@realtime.local",
+		Username: "user",
+		Password: "secret",
+		RootCAs:  roots,
+	})
+	if err != nil {
+		t.Fatalf("Error creating sender: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	event := models.AlarmEvent{
+		Event: "raised",
+		Alarm: models.Alarm{Type: "temperature", Value: 105, Limit: 100, RaisedAt: time.Now()},
+		Time:  time.Now(),
+	}
+	assert.NoError(t, sender.Send(ctx, "operator@realtime.local", event))
+
+	select {
+	case msg := <-messages:
+		assert.Contains(t, msg, "To: operator@realtime.local")
+		assert.Contains(t, msg, "Датчик: temperature")
+	default:
+		t.Fatal("No message received")
+	}
+}
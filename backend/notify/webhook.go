@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"realtime-app/models"
+)
+
+// WebhookSender отправляет событие JSON-запросом POST на URL канала
+type WebhookSender struct {
+	Client *http.Client
+}
+
+func NewWebhookSender() *WebhookSender {
+	return &WebhookSender{Client: http.DefaultClient}
+}
+
+func (s *WebhookSender) Send(ctx context.Context, target string, event models.AlarmEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package profiles
+
+import (
+	"realtime-app/models"
+	"strings"
+	"time"
+)
+
+// SelectProfile возвращает профиль, который должен быть активен в момент now:
+// из подходящих интервалов расписания выбирается интервал с наибольшим
+// приоритетом, при равенстве - созданный раньше. nil, если ни один не подходит.
+func SelectProfile(schedule []models.ProfileSchedule, now time.Time) *int {
+	var best *models.ProfileSchedule
+	for i := range schedule {
+		e := &schedule[i]
+		if !matches(*e, now) {
+			continue
+		}
+		if best == nil || e.Priority > best.Priority || (e.Priority == best.Priority && e.ID < best.ID) {
+			best = e
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	id := best.ProfileID
+	return &id
+}
+
+func matches(e models.ProfileSchedule, now time.Time) bool {
+	start, ok1 := clockSeconds(e.StartTime)
+	end, ok2 := clockSeconds(e.EndTime)
+	if !ok1 || !ok2 {
+		return false
+	}
+	t := now.Hour()*3600 + now.Minute()*60 + now.Second()
+
+	// День, к которому относится интервал: для ночного интервала после
+	// полуночи это предыдущие сутки
+	day := now
+	switch {
+	case start <= end:
+		if t < start || t >= end {
+			return false
+		}
+	case t >= start:
+	case t < end:
+		day = now.AddDate(0, 0, -1)
+	default:
+		return false
+	}
+
+	if e.Weekdays&(1<<uint(day.Weekday())) == 0 {
+		return false
+	}
+
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	if e.ValidFrom != nil && date.Before(dateOnly(*e.ValidFrom)) {
+		return false
+	}
+	if e.ValidTo != nil && date.After(dateOnly(*e.ValidTo)) {
+		return false
+	}
+	return true
+}
+
+// clockSeconds разбирает время суток ЧЧ:ММ или ЧЧ:ММ:СС
+func clockSeconds(value string) (int, bool) {
+	layout := "15:04:05"
+	if strings.Count(value, ":") == 1 {
+		layout = "15:04"
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*3600 + t.Minute()*60 + t.Second(), true
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,53 @@
+package profiles_test
+
+import (
+	"realtime-app/models"
+	"realtime-app/profiles"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	weekdaysAll     = 127
+	weekdaysWorking = 0b0111110 // пн-пт
+)
+
+func TestSelectProfilePriority(t *testing.T) {
+	schedule := []models.ProfileSchedule{
+		{ID: 1, ProfileID: 10, Weekdays: weekdaysWorking, StartTime: "08:00", EndTime: "20:00"},
+		{ID: 2, ProfileID: 20, Weekdays: weekdaysAll, StartTime: "12:00", EndTime: "13:00", Priority: 5},
+	}
+
+	// Среда
+	assert.Equal(t, 10, *profiles.SelectProfile(schedule, time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 20, *profiles.SelectProfile(schedule, time.Date(2024, 5, 15, 12, 30, 0, 0, time.UTC)))
+	assert.Nil(t, profiles.SelectProfile(schedule, time.Date(2024, 5, 15, 20, 0, 0, 0, time.UTC)))
+	// Суббота
+	assert.Nil(t, profiles.SelectProfile(schedule, time.Date(2024, 5, 18, 9, 0, 0, 0, time.UTC)))
+}
+
+func TestSelectProfileOvernight(t *testing.T) {
+	// Ночная смена с пятницы на субботу относится к пятнице
+	schedule := []models.ProfileSchedule{
+		{ID: 1, ProfileID: 30, Weekdays: 1 << uint(time.Friday), StartTime: "22:00", EndTime: "06:00"},
+	}
+
+	assert.Equal(t, 30, *profiles.SelectProfile(schedule, time.Date(2024, 5, 17, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 30, *profiles.SelectProfile(schedule, time.Date(2024, 5, 18, 5, 59, 0, 0, time.UTC)))
+	assert.Nil(t, profiles.SelectProfile(schedule, time.Date(2024, 5, 18, 6, 0, 0, 0, time.UTC)))
+	assert.Nil(t, profiles.SelectProfile(schedule, time.Date(2024, 5, 17, 5, 0, 0, 0, time.UTC)))
+}
+
+func TestSelectProfileValidityRange(t *testing.T) {
+	from := time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+	schedule := []models.ProfileSchedule{
+		{ID: 1, ProfileID: 40, Weekdays: weekdaysAll, StartTime: "00:00", EndTime: "23:59:59", ValidFrom: &from, ValidTo: &to},
+	}
+
+	assert.Nil(t, profiles.SelectProfile(schedule, time.Date(2024, 12, 29, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 40, *profiles.SelectProfile(schedule, time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)))
+	assert.Nil(t, profiles.SelectProfile(schedule, time.Date(2025, 1, 9, 12, 0, 0, 0, time.UTC)))
+}
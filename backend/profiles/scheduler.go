@@ -0,0 +1,172 @@
+package profiles
+
+import (
+	"fmt"
+	"log"
+	"realtime-app/db"
+	"realtime-app/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Автор изменений порогов, выполненных планировщиком
+const schedulerUser = "scheduler"
+
+// Scheduler переключает активный профиль порогов по расписанию. Смену
+// выполняет один экземпляр backend (тот, кто первым обновил active_threshold_profile),
+// а объявляют о ней в живом потоке все экземпляры.
+type Scheduler struct {
+	db          *sqlx.DB
+	onThreshold func(models.Threshold)
+	onSwitch    func(models.ProfileSwitch)
+
+	initialized bool
+	lastProfile *int
+}
+
+func NewScheduler(db *sqlx.DB, onThreshold func(models.Threshold), onSwitch func(models.ProfileSwitch)) *Scheduler {
+	return &Scheduler{db: db, onThreshold: onThreshold, onSwitch: onSwitch}
+}
+
+// Run проверяет расписание с заданным интервалом
+func (s *Scheduler) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Tick(time.Now()); err != nil {
+			log.Printf("Profile scheduler error: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// Tick выполняет одну проверку расписания на момент now
+func (s *Scheduler) Tick(now time.Time) error {
+	var schedule []models.ProfileSchedule
+	if err := s.db.Select(&schedule, "SELECT * FROM threshold_profile_schedule"); err != nil {
+		return err
+	}
+
+	if desired := SelectProfile(schedule, now); desired != nil {
+		if err := s.activate(*desired); err != nil {
+			return err
+		}
+	}
+
+	var active struct {
+		ProfileID  *int      `db:"profile_id"`
+		SwitchedAt time.Time `db:"switched_at"`
+	}
+	if err := s.db.Get(&active, "SELECT profile_id, switched_at FROM active_threshold_profile WHERE id = 1"); err != nil {
+		return err
+	}
+
+	// При старте запоминаем текущий профиль без объявления
+	changed := s.initialized && !sameProfile(active.ProfileID, s.lastProfile)
+	s.initialized = true
+	s.lastProfile = active.ProfileID
+	if !changed || active.ProfileID == nil {
+		return nil
+	}
+
+	profile, err := Load(s.db, *active.ProfileID)
+	if err != nil {
+		return err
+	}
+	s.onSwitch(models.ProfileSwitch{
+		ProfileID:   active.ProfileID,
+		ProfileName: profile.Name,
+		Thresholds:  profile.Values,
+		SwitchedAt:  active.SwitchedAt,
+	})
+	return nil
+}
+
+// activate делает профиль активным и применяет его пороги, если он еще не активен.
+// Смена профиля и запись порогов выполняются в одной транзакции.
+func (s *Scheduler) activate(profileID int) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE active_threshold_profile SET profile_id = $1, switched_at = CURRENT_TIMESTAMP
+		WHERE id = 1 AND profile_id IS DISTINCT FROM $1`,
+		profileID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+
+	profile, err := Load(tx, profileID)
+	if err != nil {
+		return err
+	}
+	saved, err := Apply(tx, profile, schedulerUser)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, t := range saved {
+		s.onThreshold(t)
+	}
+	return nil
+}
+
+// Apply записывает пороги профиля с фиксацией в истории изменений внутри
+// транзакции tx. Возвращает сохраненные пороги; объявлять о них следует
+// после фиксации транзакции.
+func Apply(tx *sqlx.Tx, profile models.ThresholdProfile, user string) ([]models.Threshold, error) {
+	var saved []models.Threshold
+	for _, v := range profile.Values {
+		t, err := db.SaveThresholdTx(tx, models.Threshold{
+			Type:     v.Type,
+			MinValue: v.MinValue,
+			MaxValue: v.MaxValue,
+		}, models.ThresholdChange{
+			User:   user,
+			Reason: fmt.Sprintf("profile %q", profile.Name),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка применения профиля %s: %v", profile.Name, err)
+		}
+		saved = append(saved, t)
+	}
+	return saved, nil
+}
+
+// Load читает профиль вместе с порогами и расписанием
+func Load(conn sqlx.Queryer, profileID int) (models.ThresholdProfile, error) {
+	var profile models.ThresholdProfile
+	if err := sqlx.Get(conn, &profile, "SELECT * FROM threshold_profiles WHERE id = $1", profileID); err != nil {
+		return profile, err
+	}
+
+	profile.Values = []models.ProfileValue{}
+	if err := sqlx.Select(conn, &profile.Values,
+		"SELECT * FROM threshold_profile_values WHERE profile_id = $1 ORDER BY type", profileID); err != nil {
+		return profile, err
+	}
+
+	profile.Schedule = []models.ProfileSchedule{}
+	if err := sqlx.Select(conn, &profile.Schedule,
+		"SELECT * FROM threshold_profile_schedule WHERE profile_id = $1 ORDER BY id", profileID); err != nil {
+		return profile, err
+	}
+	return profile, nil
+}
+
+func sameProfile(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
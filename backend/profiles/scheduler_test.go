@@ -0,0 +1,59 @@
+package profiles_test
+
+import (
+	"errors"
+	"realtime-app/models"
+	"realtime-app/profiles"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// Если пороги профиля записать не удалось, смена профиля откатывается,
+// и следующая проверка расписания попробует применить его снова
+func TestSchedulerRollsBackSwitchWhenApplyFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	var applied []models.Threshold
+	scheduler := profiles.NewScheduler(sqlxDB, func(th models.Threshold) {
+		applied = append(applied, th)
+	}, func(models.ProfileSwitch) {})
+
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.Local)
+	mock.ExpectQuery("SELECT \\* FROM threshold_profile_schedule").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "profile_id", "weekdays", "start_time", "end_time", "priority", "valid_from", "valid_to"}).
+			AddRow(1, 2, 127, "00:00", "23:59", 0, nil, nil))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE active_threshold_profile").
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT \\* FROM threshold_profiles").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "created_at"}).
+			AddRow(2, "night", "", now))
+	mock.ExpectQuery("SELECT \\* FROM threshold_profile_values").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"profile_id", "type", "min_value", "max_value"}).
+			AddRow(2, "temperature", 10, 50))
+	mock.ExpectQuery("SELECT \\* FROM threshold_profile_schedule").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT min_value, max_value, version FROM thresholds").
+		WithArgs("temperature").
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	err = scheduler.Tick(now)
+
+	assert.Error(t, err)
+	assert.Empty(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
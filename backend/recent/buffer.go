@@ -0,0 +1,153 @@
+package recent
+
+import (
+	"realtime-app/models"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ring - последние показания одного датчика в порядке поступления.
+// since - момент, после которого в кольце есть все показания датчика.
+type ring struct {
+	items []models.SensorData
+	next  int
+	full  bool
+	since time.Time
+}
+
+func (r *ring) add(reading models.SensorData) {
+	if r.full {
+		// Вытесненное показание - самое раннее; после него кольцо полное
+		r.since = r.items[r.next].Timestamp
+	}
+	r.items[r.next] = reading
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// ordered возвращает показания от старых к новым
+func (r *ring) ordered() []models.SensorData {
+	if !r.full {
+		return append([]models.SensorData(nil), r.items[:r.next]...)
+	}
+	out := make([]models.SensorData, 0, len(r.items))
+	out = append(out, r.items[r.next:]...)
+	return append(out, r.items[:r.next]...)
+}
+
+// Buffer хранит в памяти по size последних показаний каждого датчика.
+// Показания попадают в буфер при записи (Add), после запуска буфер
+// заполняется из sensor_data (Warm). Буфер видит только показания своего
+// экземпляра сервера.
+type Buffer struct {
+	size int
+
+	mu    sync.RWMutex
+	rings map[string]*ring
+	since time.Time // начало окна Warm; до него буфер ничего не гарантирует
+}
+
+func NewBuffer(size int) *Buffer {
+	return &Buffer{size: size, rings: make(map[string]*ring)}
+}
+
+// Warm загружает последние показания за window, не больше size на датчик
+func (b *Buffer) Warm(db *sqlx.DB, window time.Duration) error {
+	since := time.Now().Add(-window).UTC()
+	var readings []models.SensorData
+	if err := db.Select(&readings, `
+		SELECT id, value, type, timestamp, id_equipment FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY type ORDER BY timestamp DESC, id DESC) AS n
+			FROM sensor_data
+			WHERE timestamp >= $1
+		) s
+		WHERE n <= $2
+		ORDER BY type, timestamp, id`,
+		since, b.size); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.since = since
+	b.rings = make(map[string]*ring)
+	for _, r := range readings {
+		b.ringLocked(r.Type).add(r)
+	}
+	// Показания, загруженные до Warm, вытесняются не раньше загруженных,
+	// поэтому кольцо полное начиная с самого раннего из них
+	for _, r := range b.rings {
+		if r.full {
+			r.since = r.items[r.next].Timestamp
+		}
+	}
+	return nil
+}
+
+func (b *Buffer) ringLocked(sensorType string) *ring {
+	r, ok := b.rings[sensorType]
+	if !ok {
+		r = &ring{items: make([]models.SensorData, b.size), since: b.since}
+		b.rings[sensorType] = r
+	}
+	return r
+}
+
+// Add добавляет записанное показание
+func (b *Buffer) Add(reading models.SensorData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ringLocked(reading.Type).add(reading)
+}
+
+// Latest возвращает до n последних показаний датчика, новые первыми
+func (b *Buffer) Latest(sensorType string, n int) []models.SensorData {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	r, ok := b.rings[sensorType]
+	if !ok {
+		return nil
+	}
+	items := r.ordered()
+	if len(items) > n {
+		items = items[len(items)-n:]
+	}
+	out := make([]models.SensorData, len(items))
+	for i := range items {
+		out[i] = items[len(items)-1-i]
+	}
+	return out
+}
+
+// Range возвращает показания датчика с from по to (не включая) от старых
+// к новым. ok = false, если часть показаний за период уже вытеснена
+// или была до Warm и запрос нужно выполнить по sensor_data.
+func (b *Buffer) Range(sensorType string, from, to time.Time) ([]models.SensorData, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	since := b.since
+	r, ok := b.rings[sensorType]
+	if ok {
+		since = r.since
+	}
+	if since.IsZero() || !from.After(since) {
+		return nil, false
+	}
+	if !ok {
+		return []models.SensorData{}, true
+	}
+
+	out := []models.SensorData{}
+	for _, item := range r.ordered() {
+		if !item.Timestamp.Before(from) && item.Timestamp.Before(to) {
+			out = append(out, item)
+		}
+	}
+	return out, true
+}
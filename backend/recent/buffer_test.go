@@ -0,0 +1,91 @@
+package recent_test
+
+import (
+	"realtime-app/models"
+	"realtime-app/recent"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func reading(id int, sensorType string, ts time.Time) models.SensorData {
+	return models.SensorData{ID: id, Value: float64(id), Type: sensorType, Timestamp: ts}
+}
+
+func TestLatestReturnsNewestFirst(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	buffer := recent.NewBuffer(3)
+	for i := 1; i <= 5; i++ {
+		buffer.Add(reading(i, "temperature", start.Add(time.Duration(i)*time.Second)))
+	}
+	buffer.Add(reading(6, "pressure", start))
+
+	latest := buffer.Latest("temperature", 10)
+	if assert.Len(t, latest, 3) {
+		assert.Equal(t, []int{5, 4, 3}, []int{latest[0].ID, latest[1].ID, latest[2].ID})
+	}
+	assert.Len(t, buffer.Latest("temperature", 2), 2)
+	assert.Len(t, buffer.Latest("pressure", 10), 1)
+	assert.Nil(t, buffer.Latest("humidity", 10))
+}
+
+func TestRangeRequiresCoverage(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	buffer := recent.NewBuffer(3)
+	buffer.Add(reading(1, "temperature", start))
+
+	// Без Warm и вытеснений неизвестно, что было до первого показания
+	_, ok := buffer.Range("temperature", start.Add(-time.Hour), start.Add(time.Hour))
+	assert.False(t, ok)
+
+	for i := 2; i <= 4; i++ {
+		buffer.Add(reading(i, "temperature", start.Add(time.Duration(i-1)*time.Second)))
+	}
+	// Показание 1 вытеснено, после него в буфере все показания
+	_, ok = buffer.Range("temperature", start, start.Add(time.Hour))
+	assert.False(t, ok)
+	items, ok := buffer.Range("temperature", start.Add(time.Second), start.Add(3*time.Second))
+	assert.True(t, ok)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, 2, items[0].ID)
+		assert.Equal(t, 3, items[1].ID)
+	}
+}
+
+func TestWarmLoadsRecentReadings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	ts := time.Now().UTC().Add(-10 * time.Minute)
+	columns := []string{"id", "value", "type", "timestamp", "id_equipment"}
+	mock.ExpectQuery("ROW_NUMBER\\(\\) OVER").
+		WithArgs(sqlmock.AnyArg(), 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 20.0, "temperature", ts, 1).
+			AddRow(2, 21.0, "temperature", ts.Add(time.Second), 1).
+			AddRow(3, 1.5, "pressure", ts, nil))
+
+	buffer := recent.NewBuffer(2)
+	assert.NoError(t, buffer.Warm(sqlx.NewDb(db, "sqlmock"), time.Hour))
+
+	// Загружено неполное кольцо - в нем все показания окна Warm
+	items, ok := buffer.Range("pressure", ts.Add(-30*time.Minute), time.Now())
+	assert.True(t, ok)
+	assert.Len(t, items, 1)
+
+	// Кольцо заполнено - более ранние показания могли не поместиться
+	_, ok = buffer.Range("temperature", ts.Add(-time.Minute), time.Now())
+	assert.False(t, ok)
+
+	// Датчик без показаний в окне Warm
+	items, ok = buffer.Range("humidity", ts, time.Now())
+	assert.True(t, ok)
+	assert.Empty(t, items)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,167 @@
+package retention
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"realtime-app/rollup"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Policy - срок хранения по уровням: rollup.Raw для sensor_data и имена
+// уровней свертки (1m, 1h, 1d). Нулевой или отсутствующий срок - хранить всегда.
+type Policy map[string]time.Duration
+
+// Keeps сообщает, хранятся ли на момент now данные уровня level за время t
+func (p Policy) Keeps(level string, t, now time.Time) bool {
+	d := p[level]
+	return d == 0 || !t.Before(now.Add(-d))
+}
+
+// Число суточных секций sensor_data, создаваемых заранее
+const PartitionsAhead = 7
+
+const (
+	partitionPrefix = "sensor_data_p"
+	legacyPartition = "sensor_data_legacy"
+)
+
+// Job создает будущие секции sensor_data и удаляет устаревшие данные.
+// Данные уровня удаляются только после того, как свернуты в следующий
+// уровень, поэтому отставание свертки не приводит к потерям.
+type Job struct {
+	db     *sqlx.DB
+	policy Policy
+}
+
+func NewJob(db *sqlx.DB, policy Policy) *Job {
+	return &Job{db: db, policy: policy}
+}
+
+// Run выполняет обслуживание с заданным интервалом
+func (j *Job) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.Tick(); err != nil {
+			log.Printf("Retention error: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// Tick создает недостающие секции и удаляет данные старше срока хранения
+func (j *Job) Tick() error {
+	if _, err := j.db.Exec("SELECT create_sensor_data_partitions(CURRENT_DATE, $1)", PartitionsAhead); err != nil {
+		return fmt.Errorf("create partitions: %v", err)
+	}
+	if err := j.dropPartitions(); err != nil {
+		return fmt.Errorf("drop partitions: %v", err)
+	}
+	for i, res := range rollup.Resolutions {
+		next := ""
+		if i+1 < len(rollup.Resolutions) {
+			next = rollup.Resolutions[i+1].Name
+		}
+		if err := j.trimRollup(res, next); err != nil {
+			return fmt.Errorf("trim rollup %s: %v", res.Name, err)
+		}
+	}
+	return nil
+}
+
+// dropPartitions удаляет секции sensor_data, целиком лежащие до срока хранения
+func (j *Job) dropPartitions() error {
+	cutoff, err := j.cutoff(rollup.Raw, rollup.Resolutions[0].Name)
+	if err != nil || cutoff == nil {
+		return err
+	}
+
+	var partitions []string
+	if err := j.db.Select(&partitions, `
+		SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'sensor_data'::regclass
+		ORDER BY c.relname`); err != nil {
+		return err
+	}
+
+	for _, name := range partitions {
+		expired, err := j.partitionExpired(name, *cutoff)
+		if err != nil {
+			return err
+		}
+		if !expired {
+			continue
+		}
+		if _, err := j.db.Exec("DROP TABLE IF EXISTS " + pq.QuoteIdentifier(name)); err != nil {
+			return err
+		}
+		log.Printf("Dropped expired partition %s", name)
+	}
+	return nil
+}
+
+// partitionExpired проверяет, что все показания секции старше cutoff.
+// Граница суточной секции следует из имени, старую таблицу проверяем по данным.
+func (j *Job) partitionExpired(name string, cutoff time.Time) (bool, error) {
+	if name == legacyPartition {
+		var last sql.NullTime
+		if err := j.db.Get(&last, "SELECT MAX(timestamp) FROM "+legacyPartition); err != nil {
+			return false, err
+		}
+		return !last.Valid || last.Time.Before(cutoff), nil
+	}
+
+	// Секции, созданные вручную, не трогаем
+	if !strings.HasPrefix(name, partitionPrefix) {
+		return false, nil
+	}
+	day, err := time.Parse("20060102", strings.TrimPrefix(name, partitionPrefix))
+	if err != nil {
+		return false, nil
+	}
+	return !day.AddDate(0, 0, 1).After(cutoff), nil
+}
+
+// trimRollup удаляет устаревшие интервалы свертки res
+func (j *Job) trimRollup(res rollup.Resolution, next string) error {
+	cutoff, err := j.cutoff(res.Name, next)
+	if err != nil || cutoff == nil {
+		return err
+	}
+	_, err = j.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE bucket < $1", res.Table), *cutoff)
+	return err
+}
+
+// cutoff возвращает границу хранения уровня level, не позднее позиции свертки
+// уровня guard ("" - без ограничения). nil - удалять нечего.
+func (j *Job) cutoff(level, guard string) (*time.Time, error) {
+	keep := j.policy[level]
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	var cutoff sql.NullTime
+	var err error
+	if guard == "" {
+		err = j.db.Get(&cutoff, "SELECT LOCALTIMESTAMP - $1 * INTERVAL '1 second'", keep.Seconds())
+	} else {
+		err = j.db.Get(&cutoff, `
+			SELECT LEAST(LOCALTIMESTAMP - $1 * INTERVAL '1 second', rolled_up_to)
+			FROM sensor_rollup_state WHERE resolution = $2 AND rolled_up_to IS NOT NULL`,
+			keep.Seconds(), guard)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+	}
+	if err != nil || !cutoff.Valid {
+		return nil, err
+	}
+	return &cutoff.Time, nil
+}
@@ -0,0 +1,53 @@
+package retention_test
+
+import (
+	"realtime-app/retention"
+	"realtime-app/rollup"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTickDropsExpiredPartitions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	day := 24 * time.Hour
+	policy := retention.Policy{rollup.Raw: 30 * day, "1h": 730 * day}
+	cutoff := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("SELECT create_sensor_data_partitions").
+		WithArgs(retention.PartitionsAhead).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Сырые данные удаляются не позже позиции минутной свертки
+	mock.ExpectQuery("FROM sensor_rollup_state").
+		WithArgs((30 * day).Seconds(), "1m").
+		WillReturnRows(sqlmock.NewRows([]string{"least"}).AddRow(cutoff))
+	mock.ExpectQuery("FROM pg_inherits").
+		WillReturnRows(sqlmock.NewRows([]string{"relname"}).
+			AddRow("sensor_data_legacy").
+			AddRow("sensor_data_p20240301").
+			AddRow("sensor_data_p20240302").
+			AddRow("sensor_data_archive"))
+	mock.ExpectQuery("SELECT MAX\\(timestamp\\) FROM sensor_data_legacy").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(cutoff.Add(-48 * time.Hour)))
+	mock.ExpectExec(`DROP TABLE IF EXISTS "sensor_data_legacy"`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DROP TABLE IF EXISTS "sensor_data_p20240301"`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Часовая свертка ждет суточную; минутная и суточная хранятся всегда
+	mock.ExpectQuery("FROM sensor_rollup_state").
+		WithArgs((730 * day).Seconds(), "1d").
+		WillReturnRows(sqlmock.NewRows([]string{"least"}))
+
+	assert.NoError(t, retention.NewJob(sqlx.NewDb(db, "sqlmock"), policy).Tick())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,155 @@
+package rollup
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Resolution - уровень свертки показаний sensor_data. Каждый уровень
+// строится из предыдущего (1m - из сырых данных), границы интервалов
+// отсчитываются от начала эпохи, как и в запросах агрегации.
+type Resolution struct {
+	Name  string
+	Step  time.Duration
+	Table string
+	// Единица date_trunc, соответствующая Step
+	unit string
+}
+
+// Raw обозначает сырые данные sensor_data
+const Raw = "raw"
+
+// Resolutions упорядочены от мелкого уровня к крупному
+var Resolutions = []Resolution{
+	{Name: "1m", Step: time.Minute, Table: "sensor_rollup_1m", unit: "minute"},
+	{Name: "1h", Step: time.Hour, Table: "sensor_rollup_1h", unit: "hour"},
+	{Name: "1d", Step: 24 * time.Hour, Table: "sensor_rollup_1d", unit: "day"},
+}
+
+// Запас времени на запись показаний, пришедших с опозданием: минута
+// сворачивается не раньше, чем через Grace после ее окончания
+const Grace = 10 * time.Second
+
+// For выбирает самый крупный уровень, из которого без потерь собираются
+// интервалы длиной bucket; nil - агрегировать сырые данные
+func For(bucket time.Duration) *Resolution {
+	for i := len(Resolutions) - 1; i >= 0; i-- {
+		if bucket >= Resolutions[i].Step && bucket%Resolutions[i].Step == 0 {
+			return &Resolutions[i]
+		}
+	}
+	return nil
+}
+
+// Roller дописывает свертки по мере поступления данных. Позиция каждого
+// уровня хранится в sensor_rollup_state и блокируется на время свертки,
+// поэтому несколько экземпляров backend не обрабатывают интервал дважды.
+type Roller struct {
+	db *sqlx.DB
+}
+
+func NewRoller(db *sqlx.DB) *Roller {
+	return &Roller{db: db}
+}
+
+// Run сворачивает новые данные с заданным интервалом
+func (r *Roller) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Tick(); err != nil {
+			log.Printf("Rollup error: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// Tick сворачивает все завершенные интервалы каждого уровня
+func (r *Roller) Tick() error {
+	for i := range Resolutions {
+		if err := r.rollLevel(i); err != nil {
+			return fmt.Errorf("rollup %s: %v", Resolutions[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Roller) rollLevel(level int) error {
+	res := Resolutions[level]
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var from sql.NullTime
+	if err := tx.Get(&from, "SELECT rolled_up_to FROM sensor_rollup_state WHERE resolution = $1 FOR UPDATE", res.Name); err != nil {
+		return err
+	}
+
+	// Сырые данные готовы до текущего момента за вычетом запаса,
+	// свертка предыдущего уровня - до ее собственной позиции
+	var to sql.NullTime
+	if level == 0 {
+		err = tx.Get(&to, "SELECT date_trunc($1, LOCALTIMESTAMP - $2 * INTERVAL '1 second')",
+			res.unit, Grace.Seconds())
+	} else {
+		err = tx.Get(&to, "SELECT date_trunc($1, rolled_up_to) FROM sensor_rollup_state WHERE resolution = $2",
+			res.unit, Resolutions[level-1].Name)
+	}
+	if err != nil {
+		return err
+	}
+	if !to.Valid || (from.Valid && !to.Time.After(from.Time)) {
+		return nil
+	}
+
+	var fromArg interface{}
+	if from.Valid {
+		fromArg = from.Time
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`
+		INSERT INTO %s (type, equipment_id, bucket, min, max, sum, count, first, last)
+		%s
+		ON CONFLICT (type, equipment_id, bucket) DO UPDATE SET
+			min = EXCLUDED.min, max = EXCLUDED.max, sum = EXCLUDED.sum, count = EXCLUDED.count,
+			first = EXCLUDED.first, last = EXCLUDED.last`,
+		res.Table, sourceQuery(level)),
+		fromArg, to.Time); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE sensor_rollup_state SET rolled_up_to = $2 WHERE resolution = $1", res.Name, to.Time); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sourceQuery сводит данные предыдущего уровня за [$1, $2) в интервалы уровня level
+func sourceQuery(level int) string {
+	res := Resolutions[level]
+	if level == 0 {
+		return fmt.Sprintf(`
+		SELECT type, COALESCE(id_equipment, 0), date_trunc('%s', timestamp) AS bucket,
+		       MIN(value), MAX(value), SUM(value), COUNT(*),
+		       (ARRAY_AGG(value ORDER BY timestamp, id))[1],
+		       (ARRAY_AGG(value ORDER BY timestamp DESC, id DESC))[1]
+		FROM sensor_data
+		WHERE ($1::timestamp IS NULL OR timestamp >= $1) AND timestamp < $2
+		GROUP BY 1, 2, 3`, res.unit)
+	}
+	return fmt.Sprintf(`
+		SELECT type, equipment_id, date_trunc('%s', bucket) AS rolled,
+		       MIN(min), MAX(max), SUM(sum), SUM(count),
+		       (ARRAY_AGG(first ORDER BY bucket))[1],
+		       (ARRAY_AGG(last ORDER BY bucket DESC))[1]
+		FROM %s
+		WHERE ($1::timestamp IS NULL OR bucket >= $1) AND bucket < $2
+		GROUP BY 1, 2, 3`, res.unit, Resolutions[level-1].Table)
+}
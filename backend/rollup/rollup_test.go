@@ -0,0 +1,76 @@
+package rollup_test
+
+import (
+	"realtime-app/rollup"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFor(t *testing.T) {
+	assert.Nil(t, rollup.For(30*time.Second))
+	assert.Nil(t, rollup.For(90*time.Second))
+	assert.Equal(t, "1m", rollup.For(15*time.Minute).Name)
+	assert.Equal(t, "1h", rollup.For(6*time.Hour).Name)
+	assert.Equal(t, "1d", rollup.For(24*time.Hour).Name)
+}
+
+func TestTickRollsCompletedBuckets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mark := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := mark.Add(5 * time.Minute)
+
+	// Минутная свертка догоняет сырые данные
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rolled_up_to FROM sensor_rollup_state").
+		WithArgs("1m").
+		WillReturnRows(sqlmock.NewRows([]string{"rolled_up_to"}).AddRow(mark))
+	mock.ExpectQuery("LOCALTIMESTAMP").
+		WithArgs("minute", rollup.Grace.Seconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"date_trunc"}).AddRow(now))
+	mock.ExpectExec("INSERT INTO sensor_rollup_1m .* FROM sensor_data").
+		WithArgs(mark, now).
+		WillReturnResult(sqlmock.NewResult(0, 15))
+	mock.ExpectExec("UPDATE sensor_rollup_state").
+		WithArgs("1m", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Час еще не завершен - часовая свертка ждет
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rolled_up_to FROM sensor_rollup_state").
+		WithArgs("1h").
+		WillReturnRows(sqlmock.NewRows([]string{"rolled_up_to"}).AddRow(mark))
+	mock.ExpectQuery("SELECT date_trunc").
+		WithArgs("hour", "1m").
+		WillReturnRows(sqlmock.NewRows([]string{"date_trunc"}).AddRow(mark))
+	mock.ExpectRollback()
+
+	// Суточная свертка еще не начиналась и строится с самого начала
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rolled_up_to FROM sensor_rollup_state").
+		WithArgs("1d").
+		WillReturnRows(sqlmock.NewRows([]string{"rolled_up_to"}).AddRow(nil))
+	mock.ExpectQuery("SELECT date_trunc").
+		WithArgs("day", "1h").
+		WillReturnRows(sqlmock.NewRows([]string{"date_trunc"}).AddRow(day))
+	mock.ExpectExec("INSERT INTO sensor_rollup_1d .* FROM sensor_rollup_1h").
+		WithArgs(nil, day).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("UPDATE sensor_rollup_state").
+		WithArgs("1d", day).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, rollup.NewRoller(sqlx.NewDb(db, "sqlmock")).Tick())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
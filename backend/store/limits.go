@@ -0,0 +1,146 @@
+package store
+
+import (
+	"realtime-app/models"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// limitKey - граница параметра конкретного оборудования
+type limitKey struct {
+	sensorType  string
+	equipmentID int
+}
+
+// LoadLimits загружает границы по оборудованию из reference_parameters и
+// привязку датчиков к оборудованию
+func (s *ThresholdStore) LoadLimits(db *sqlx.DB) error {
+	var limits []models.EffectiveLimit
+	if err := db.Select(&limits, `
+		SELECT pp.sensor_type AS type, pp.id_equipment AS equipment_id, rp.id_param AS param_id,
+		       rp.min_value, rp.max_value,
+		       CASE WHEN rp.template_id IS NULL THEN $1 ELSE $2 END AS source
+		FROM reference_parameters rp
+		JOIN process_parameters pp ON pp.id = rp.id_param
+		WHERE pp.sensor_type IS NOT NULL AND pp.id_equipment IS NOT NULL`,
+		models.LimitSourceEquipment, models.LimitSourceTemplate); err != nil {
+		return err
+	}
+
+	var bindings []struct {
+		Type        string `db:"type"`
+		EquipmentID int    `db:"id_equipment"`
+	}
+	if err := db.Select(&bindings, "SELECT type, id_equipment FROM sensor_equipment"); err != nil {
+		return err
+	}
+
+	overrides := make(map[limitKey]models.EffectiveLimit, len(limits))
+	for _, l := range limits {
+		overrides[limitKey{l.Type, *l.EquipmentID}] = l
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bindings = make(map[string]int, len(bindings))
+	for _, b := range bindings {
+		s.bindings[b.Type] = b.EquipmentID
+	}
+
+	// Уведомляем подписчиков о типах, у которых изменились границы оборудования
+	changed := make(map[string]bool)
+	for key, l := range overrides {
+		if old, ok := s.overrides[key]; !ok || old.MinValue != l.MinValue || old.MaxValue != l.MaxValue {
+			changed[key.sensorType] = true
+		}
+	}
+	for key := range s.overrides {
+		if _, ok := overrides[key]; !ok {
+			changed[key.sensorType] = true
+		}
+	}
+	s.overrides = overrides
+
+	for sensorType := range changed {
+		t := s.thresholds[sensorType]
+		t.Type = sensorType
+		s.notify(t)
+	}
+	return nil
+}
+
+// EquipmentFor возвращает оборудование, на котором установлен датчик
+func (s *ThresholdStore) EquipmentFor(sensorType string) *int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if id, ok := s.bindings[sensorType]; ok {
+		return &id
+	}
+	return nil
+}
+
+// Effective возвращает действующий порог для показания: границу параметра
+// оборудования, если она задана, иначе общий порог по типу датчика
+func (s *ThresholdStore) Effective(sensorType string, equipmentID *int) (models.Threshold, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.thresholds[sensorType]
+	if equipmentID != nil {
+		if l, found := s.overrides[limitKey{sensorType, *equipmentID}]; found {
+			t.Type = sensorType
+			t.MinValue, t.MaxValue = l.MinValue, l.MaxValue
+			return t, true
+		}
+	}
+	return t, ok
+}
+
+// EffectiveLimits возвращает действующие границы для каждого датчика на его
+// оборудовании, а также все заданные границы по оборудованию
+func (s *ThresholdStore) EffectiveLimits() []models.EffectiveLimit {
+	s.mu.RLock()
+	result := make([]models.EffectiveLimit, 0, len(s.thresholds)+len(s.overrides))
+	covered := make(map[limitKey]bool)
+	for sensorType, t := range s.thresholds {
+		limit := models.EffectiveLimit{
+			Type:     sensorType,
+			MinValue: t.MinValue,
+			MaxValue: t.MaxValue,
+			Source:   models.LimitSourceType,
+		}
+		if id, ok := s.bindings[sensorType]; ok {
+			equipmentID := id
+			limit.EquipmentID = &equipmentID
+			if l, found := s.overrides[limitKey{sensorType, id}]; found {
+				limit = l
+			}
+			covered[limitKey{sensorType, id}] = true
+		}
+		result = append(result, limit)
+	}
+	for key, l := range s.overrides {
+		if !covered[key] {
+			result = append(result, l)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return equipmentOrder(result[i]) < equipmentOrder(result[j])
+	})
+	return result
+}
+
+func equipmentOrder(l models.EffectiveLimit) int {
+	if l.EquipmentID == nil {
+		return -1
+	}
+	return *l.EquipmentID
+}
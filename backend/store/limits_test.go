@@ -0,0 +1,60 @@
+package store_test
+
+import (
+	"realtime-app/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectiveLimitsFallBackToType(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM reference_parameters rp").
+		WithArgs(models.LimitSourceEquipment, models.LimitSourceTemplate).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "equipment_id", "param_id", "min_value", "max_value", "source"}).
+			AddRow("temperature", 2, 5, 22, 30, models.LimitSourceEquipment).
+			AddRow("temperature", 7, 9, 10, 50, models.LimitSourceEquipment))
+	mock.ExpectQuery("SELECT type, id_equipment FROM sensor_equipment").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id_equipment"}).
+			AddRow("temperature", 2).
+			AddRow("humidity", 1))
+
+	s := newStore()
+	assert.NoError(t, s.LoadLimits(sqlx.NewDb(db, "sqlmock")))
+
+	// Граница оборудования важнее общего порога
+	eq := s.EquipmentFor("temperature")
+	if assert.NotNil(t, eq) {
+		assert.Equal(t, 2, *eq)
+	}
+	limit, ok := s.Effective("temperature", eq)
+	assert.True(t, ok)
+	assert.Equal(t, 22.0, limit.MinValue)
+	assert.Equal(t, 30.0, limit.MaxValue)
+
+	// Без собственных границ действует порог типа
+	one := 1
+	limit, ok = s.Effective("humidity", &one)
+	assert.True(t, ok)
+	assert.Equal(t, 80.0, limit.MaxValue)
+
+	limit, _ = s.Effective("temperature", nil)
+	assert.Equal(t, 35.0, limit.MaxValue)
+
+	limits := s.EffectiveLimits()
+	if assert.Len(t, limits, 3) {
+		assert.Equal(t, "humidity", limits[0].Type)
+		assert.Equal(t, models.LimitSourceType, limits[0].Source)
+		assert.Equal(t, 2, *limits[1].EquipmentID)
+		assert.Equal(t, models.LimitSourceEquipment, limits[1].Source)
+		assert.Equal(t, 7, *limits[2].EquipmentID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"database/sql"
+	"log"
+	"realtime-app/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Каналы NOTIFY: триггер на thresholds пишет тип измененного порога,
+// триггер на reference_parameters сообщает об изменении границ по оборудованию
+const (
+	ThresholdsChannel = "thresholds_changed"
+	LimitsChannel     = "limits_changed"
+)
+
+// Listen подписывается на изменения порогов и границ по оборудованию в БД и
+// перечитывает их, чтобы все экземпляры backend видели одни и те же значения.
+// Блокируется до ошибки подписки.
+func (s *ThresholdStore) Listen(connStr string, db *sqlx.DB) error {
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Printf("Thresholds listener error: %v", err)
+			}
+		})
+	defer listener.Close()
+
+	if err := listener.Listen(ThresholdsChannel); err != nil {
+		return err
+	}
+	if err := listener.Listen(LimitsChannel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case n := <-listener.Notify:
+			// nil приходит после переподключения: уведомления могли быть потеряны
+			if n == nil {
+				if err := s.Load(db); err != nil {
+					log.Printf("Thresholds reload error: %v", err)
+				}
+				if err := s.LoadLimits(db); err != nil {
+					log.Printf("Limits reload error: %v", err)
+				}
+				continue
+			}
+			switch n.Channel {
+			case LimitsChannel:
+				if err := s.LoadLimits(db); err != nil {
+					log.Printf("Limits reload error: %v", err)
+				}
+			default:
+				if err := s.reload(db, n.Extra); err != nil {
+					log.Printf("Threshold %s reload error: %v", n.Extra, err)
+				}
+			}
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
+
+// reload перечитывает из БД порог одного типа
+func (s *ThresholdStore) reload(db *sqlx.DB, sensorType string) error {
+	var t models.Threshold
+	err := db.Get(&t, "SELECT * FROM thresholds WHERE type = $1", sensorType)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.set(t)
+	s.mu.Unlock()
+	return nil
+}
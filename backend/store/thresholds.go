@@ -0,0 +1,129 @@
+package store
+
+import (
+	"realtime-app/models"
+	"realtime-app/validation"
+	"sort"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Размер буфера уведомлений одного подписчика
+const subscriberBuffer = 16
+
+// ThresholdStore - потокобезопасное хранилище текущих порогов в памяти.
+// Используется генератором данных, HTTP обработчиками и логикой тревог.
+type ThresholdStore struct {
+	mu         sync.RWMutex
+	thresholds map[string]models.Threshold
+	overrides  map[limitKey]models.EffectiveLimit // границы по оборудованию
+	bindings   map[string]int                     // тип датчика -> оборудование
+	subs       map[chan models.Threshold]struct{}
+}
+
+func NewThresholdStore(defaults []models.Threshold) *ThresholdStore {
+	s := &ThresholdStore{
+		thresholds: make(map[string]models.Threshold),
+		overrides:  make(map[limitKey]models.EffectiveLimit),
+		bindings:   make(map[string]int),
+		subs:       make(map[chan models.Threshold]struct{}),
+	}
+	for _, t := range defaults {
+		s.thresholds[t.Type] = t
+	}
+	return s
+}
+
+// Load загружает пороги из БД поверх значений по умолчанию
+func (s *ThresholdStore) Load(db *sqlx.DB) error {
+	var thresholds []models.Threshold
+	if err := db.Select(&thresholds, "SELECT * FROM thresholds"); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range thresholds {
+		s.set(t)
+	}
+	return nil
+}
+
+// Get возвращает порог для типа датчика
+func (s *ThresholdStore) Get(sensorType string) (models.Threshold, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.thresholds[sensorType]
+	return t, ok
+}
+
+// Snapshot возвращает копию всех порогов, отсортированную по типу
+func (s *ThresholdStore) Snapshot() []models.Threshold {
+	s.mu.RLock()
+	result := make([]models.Threshold, 0, len(s.thresholds))
+	for _, t := range s.thresholds {
+		result = append(result, t)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// Update проверяет и сохраняет порог, после чего уведомляет подписчиков
+func (s *ThresholdStore) Update(t models.Threshold) error {
+	if err := validation.Threshold(t); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.set(t)
+	s.mu.Unlock()
+	return nil
+}
+
+// set сохраняет порог и уведомляет подписчиков, если границы изменились.
+// Порог из БД не старше сохраненного (по Version) пропускается: локальное
+// обновление могло прийти позже более нового значения из LISTEN/NOTIFY.
+// Version 0 - порог не из БД, он сохраняется всегда. Вызывается под блокировкой.
+func (s *ThresholdStore) set(t models.Threshold) {
+	current, ok := s.thresholds[t.Type]
+	if ok && t.Version != 0 && t.Version <= current.Version {
+		return
+	}
+	s.thresholds[t.Type] = t
+	if ok && current.MinValue == t.MinValue && current.MaxValue == t.MaxValue {
+		return
+	}
+	s.notify(t)
+}
+
+// notify уведомляет подписчиков об изменении. Вызывается под блокировкой.
+func (s *ThresholdStore) notify(t models.Threshold) {
+	for ch := range s.subs {
+		select {
+		case ch <- t:
+		default:
+		}
+	}
+}
+
+// Subscribe возвращает канал изменений порогов и функцию отписки
+func (s *ThresholdStore) Subscribe() (<-chan models.Threshold, func()) {
+	ch := make(chan models.Threshold, subscriberBuffer)
+
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subs, ch)
+			s.mu.Unlock()
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package store_test
+
+import (
+	"math"
+	"realtime-app/models"
+	"realtime-app/store"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStore() *store.ThresholdStore {
+	return store.NewThresholdStore([]models.Threshold{
+		{Type: "temperature", MinValue: 20, MaxValue: 35},
+		{Type: "humidity", MinValue: 30, MaxValue: 80},
+	})
+}
+
+func TestThresholdStoreUpdateValidates(t *testing.T) {
+	s := newStore()
+
+	invalid := []models.Threshold{
+		{Type: "", MinValue: 1, MaxValue: 2},
+		{Type: "voltage", MinValue: 1, MaxValue: 2},
+		{Type: "temperature", MinValue: 40, MaxValue: 30},
+		{Type: "temperature", MinValue: math.NaN(), MaxValue: 30},
+	}
+	for _, th := range invalid {
+		assert.Error(t, s.Update(th), "%+v", th)
+	}
+
+	current, ok := s.Get("temperature")
+	assert.True(t, ok)
+	assert.Equal(t, 35.0, current.MaxValue)
+
+	assert.NoError(t, s.Update(models.Threshold{Type: "temperature", MinValue: 25, MaxValue: 40}))
+	current, _ = s.Get("temperature")
+	assert.Equal(t, 40.0, current.MaxValue)
+}
+
+func TestThresholdStoreSnapshotSorted(t *testing.T) {
+	s := newStore()
+	assert.NoError(t, s.Update(models.Threshold{Type: "pressure", MinValue: 900, MaxValue: 1100}))
+
+	snapshot := s.Snapshot()
+	if assert.Len(t, snapshot, 3) {
+		assert.Equal(t, "humidity", snapshot[0].Type)
+		assert.Equal(t, "pressure", snapshot[1].Type)
+		assert.Equal(t, "temperature", snapshot[2].Type)
+	}
+}
+
+func TestThresholdStoreSubscribe(t *testing.T) {
+	s := newStore()
+	changes, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	assert.NoError(t, s.Update(models.Threshold{Type: "humidity", MinValue: 35, MaxValue: 75}))
+
+	select {
+	case changed := <-changes:
+		assert.Equal(t, "humidity", changed.Type)
+		assert.Equal(t, 75.0, changed.MaxValue)
+	case <-time.After(time.Second):
+		t.Fatal("no change notification received")
+	}
+}
+
+func TestThresholdStoreConcurrentAccess(t *testing.T) {
+	s := newStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Update(models.Threshold{Type: "temperature", MinValue: float64(i), MaxValue: float64(100 + j)})
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Get("temperature")
+				s.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	current, ok := s.Get("temperature")
+	assert.True(t, ok)
+	assert.Less(t, current.MinValue, current.MaxValue)
+}
+
+func TestThresholdStoreLoadNotifiesChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock DB: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM thresholds").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "min_value", "max_value"}).
+			AddRow("temperature", 20, 35). // не изменился
+			AddRow("humidity", 40, 70))
+
+	s := newStore()
+	changes, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	assert.NoError(t, s.Load(sqlx.NewDb(db, "sqlmock")))
+
+	select {
+	case changed := <-changes:
+		assert.Equal(t, "humidity", changed.Type)
+	case <-time.After(time.Second):
+		t.Fatal("no change notification received")
+	}
+	select {
+	case changed := <-changes:
+		t.Fatalf("unexpected notification for %s", changed.Type)
+	default:
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestThresholdStoreIgnoresStaleVersion(t *testing.T) {
+	s := newStore()
+
+	// Новое значение пришло через LISTEN/NOTIFY раньше локального обновления
+	assert.NoError(t, s.Update(models.Threshold{Type: "temperature", MinValue: 22, MaxValue: 45, Version: 5}))
+	assert.NoError(t, s.Update(models.Threshold{Type: "temperature", MinValue: 21, MaxValue: 40, Version: 4}))
+
+	current, _ := s.Get("temperature")
+	assert.Equal(t, 5, current.Version)
+	assert.Equal(t, 45.0, current.MaxValue)
+}
@@ -0,0 +1,50 @@
+package stream
+
+import "sync"
+
+// Размер буфера сообщений одного подписчика
+const subscriberBuffer = 16
+
+// Hub рассылает сообщения живого потока всем подключенным клиентам
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[chan interface{}]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[chan interface{}]struct{})}
+}
+
+// Subscribe регистрирует нового подписчика. Возвращает канал сообщений и
+// функцию отписки, которую нужно вызвать при закрытии соединения.
+func (h *Hub) Subscribe() (<-chan interface{}, func()) {
+	ch := make(chan interface{}, subscriberBuffer)
+
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish отправляет сообщение всем подписчикам. Медленные подписчики
+// с переполненным буфером пропускают сообщение, чтобы не блокировать остальных.
+func (h *Hub) Publish(msg interface{}) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
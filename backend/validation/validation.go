@@ -0,0 +1,142 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"realtime-app/models"
+	"strings"
+)
+
+// Коды ошибок проверки, по которым клиент определяет причину отказа
+const (
+	CodeInvalidBody  = "invalid_body"  // тело запроса не разбирается как JSON
+	CodeRequired     = "required"      // поле не задано
+	CodeInvalidValue = "invalid_value" // значение недопустимо или неверного типа
+	CodeUnknownType  = "unknown_type"  // неизвестный тип датчика
+	CodeNotFinite    = "not_finite"    // NaN или бесконечность
+	CodeInvalidRange = "invalid_range" // min_value не меньше max_value
+	CodeOutOfRange   = "out_of_range"  // число вне допустимых пределов
+)
+
+// Error - ошибка проверки входных данных с указанием поля
+type Error struct {
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+func New(code, field, message string) *Error {
+	return &Error{Code: code, Field: field, Message: message}
+}
+
+// Required - ошибка отсутствующего обязательного поля
+func Required(field string) *Error {
+	return New(CodeRequired, field, field+" is required")
+}
+
+// NotEmpty проверяет, что строковое поле задано
+func NotEmpty(field, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return Required(field)
+	}
+	return nil
+}
+
+// Positive проверяет, что идентификатор или счетчик задан и больше нуля
+func Positive(field string, value int) error {
+	if value == 0 {
+		return Required(field)
+	}
+	if value < 0 {
+		return New(CodeOutOfRange, field, field+" must be positive")
+	}
+	return nil
+}
+
+// Prefix добавляет к полю ошибки путь вложенного объекта, например values[2]
+func Prefix(err error, path string) error {
+	e, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+	prefixed := *e
+	if prefixed.Field == "" {
+		prefixed.Field = path
+	} else {
+		prefixed.Field = path + "." + prefixed.Field
+	}
+	return &prefixed
+}
+
+// Threshold проверяет тип датчика и корректность границ
+func Threshold(t models.Threshold) error {
+	if err := SensorType("type", t.Type); err != nil {
+		return err
+	}
+	return Range(t.MinValue, t.MaxValue)
+}
+
+// SensorType проверяет, что значение - известный тип датчика
+func SensorType(field, value string) error {
+	if value == "" {
+		return Required(field)
+	}
+	if _, err := models.ParseSensorType(value); err != nil {
+		return New(CodeUnknownType, field, err.Error())
+	}
+	return nil
+}
+
+// Range проверяет, что границы конечны и min_value < max_value
+func Range(minValue, maxValue float64) error {
+	return Bounds("min_value", "max_value", minValue, maxValue)
+}
+
+// Bounds - Range для полей с другими именами
+func Bounds(minField, maxField string, minValue, maxValue float64) error {
+	if math.IsNaN(minValue) || math.IsInf(minValue, 0) {
+		return New(CodeNotFinite, minField, minField+" must be a finite number")
+	}
+	if math.IsNaN(maxValue) || math.IsInf(maxValue, 0) {
+		return New(CodeNotFinite, maxField, maxField+" must be a finite number")
+	}
+	if minValue >= maxValue {
+		return New(CodeInvalidRange, minField, minField+" must be less than "+maxField)
+	}
+	return nil
+}
+
+// Between проверяет, что целое значение лежит в [min, max]
+func Between(field string, value, min, max int) error {
+	if value < min || value > max {
+		return New(CodeOutOfRange, field, fmt.Sprintf("%s must be between %d and %d", field, min, max))
+	}
+	return nil
+}
+
+// OneOf проверяет, что значение входит в список допустимых
+func OneOf(field, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return New(CodeInvalidValue, field, fmt.Sprintf("%s must be one of: %s", field, strings.Join(allowed, ", ")))
+}
+
+// First возвращает первую ошибку из списка проверок
+func First(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package validation_test
+
+import (
+	"math"
+	"realtime-app/models"
+	"realtime-app/validation"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreshold(t *testing.T) {
+	cases := []struct {
+		threshold models.Threshold
+		code      string
+		field     string
+	}{
+		{models.Threshold{MinValue: 1, MaxValue: 2}, validation.CodeRequired, "type"},
+		{models.Threshold{Type: "voltage", MinValue: 1, MaxValue: 2}, validation.CodeUnknownType, "type"},
+		{models.Threshold{Type: "temperature", MinValue: math.NaN(), MaxValue: 2}, validation.CodeNotFinite, "min_value"},
+		{models.Threshold{Type: "temperature", MinValue: 1, MaxValue: math.Inf(1)}, validation.CodeNotFinite, "max_value"},
+		{models.Threshold{Type: "temperature", MinValue: 40, MaxValue: 30}, validation.CodeInvalidRange, "min_value"},
+	}
+	for _, c := range cases {
+		err := validation.Threshold(c.threshold)
+		if assert.IsType(t, &validation.Error{}, err, "%+v", c.threshold) {
+			assert.Equal(t, c.code, err.(*validation.Error).Code)
+			assert.Equal(t, c.field, err.(*validation.Error).Field)
+		}
+	}
+
+	assert.NoError(t, validation.Threshold(models.Threshold{Type: "temperature", MinValue: 20, MaxValue: 35}))
+}
+
+func TestPrefix(t *testing.T) {
+	err := validation.Prefix(validation.Range(5, 1), "values[2]")
+	assert.Equal(t, "values[2].min_value", err.(*validation.Error).Field)
+	assert.Nil(t, validation.Prefix(nil, "values[0]"))
+}
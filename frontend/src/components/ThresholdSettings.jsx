@@ -10,7 +10,7 @@ import {
     Alert,
     CircularProgress
 } from '@mui/material';
-import { ThresholdsContext } from '../context/ThresholdsContext';
+import { ThresholdsContext, ifMatch } from '../context/ThresholdsContext';
 
 const TYPES = [
     { id: 'temperature', label: 'Температура', unit: '°C' },
@@ -18,12 +18,16 @@ const TYPES = [
     { id: 'pressure', label: 'Давление', unit: 'hPa' }
 ];
 
+// Поля ошибки API (code, field, message) -> поля формы
+const API_FIELDS = { min_value: 'min', max_value: 'max' };
+
 
 export default function ThresholdSettings() {
     const { thresholds, updateThreshold,syncThresholds, error } = useContext(ThresholdsContext);
     const [localThresholds, setLocalThresholds] = useState({});
     const [snackbar, setSnackbar] = useState({ open: false, message: '', severity: 'success' });
     const [saving, setSaving] = useState({});
+    const [fieldErrors, setFieldErrors] = useState({});
 
     useEffect(() => {
         if (thresholds) {
@@ -38,6 +42,7 @@ export default function ThresholdSettings() {
         if (isNaN(numValue)) return;
         console.log(type, field, value);
         setIsDirty(prev => ({ ...prev, [type]: true }));
+        setFieldErrors(prev => ({ ...prev, [type]: {} }));
 
         setLocalThresholds(prev => ({
             ...prev,
@@ -73,7 +78,8 @@ export default function ThresholdSettings() {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
-                    'Accept': 'application/json'
+                    'Accept': 'application/json',
+                    'If-Match': ifMatch(thresholds[type]?.version)
                 },
                 body: JSON.stringify({ 
                     type, 
@@ -82,8 +88,26 @@ export default function ThresholdSettings() {
                 }),
             });
     
+            if (response.status === 409) {
+                const conflict = await response.json();
+                const current = {
+                    min: conflict.current.min_value,
+                    max: conflict.current.max_value,
+                    version: conflict.current.version
+                };
+                setIsDirty(prev => ({ ...prev, [type]: false }));
+                setLocalThresholds(prev => ({ ...prev, [type]: current }));
+                syncThresholds({ ...thresholds, [type]: current });
+                throw new Error('Порог уже изменен другим пользователем. Загружены актуальные значения');
+            }
+
             if (!response.ok) {
-                throw new Error(await response.text());
+                const apiError = await response.json().catch(() => null);
+                const field = API_FIELDS[apiError?.field];
+                if (field) {
+                    setFieldErrors(prev => ({ ...prev, [type]: { [field]: apiError.message } }));
+                }
+                throw new Error(apiError?.message || response.statusText);
             }
 
             const updatedResponse = await fetch('http://localhost:8080/api/thresholds');
@@ -93,7 +117,8 @@ export default function ThresholdSettings() {
             updatedData.forEach(t => {
                 thresholdsMap[t.type] = { 
                     min: t.min_value, 
-                    max: t.max_value 
+                    max: t.max_value,
+                    version: t.version
                 };
             });
             
@@ -146,6 +171,8 @@ export default function ThresholdSettings() {
                             type="number"
                             value={localThresholds[id]?.min || ''}
                             onChange={(e) => handleChange(id, 'min', e.target.value)}
+                            error={Boolean(fieldErrors[id]?.min)}
+                            helperText={fieldErrors[id]?.min}
                             fullWidth
                             margin="normal"
                             InputLabelProps={{ shrink: true }}
@@ -156,6 +183,8 @@ export default function ThresholdSettings() {
                             type="number"
                             value={localThresholds[id]?.max || ''}
                             onChange={(e) => handleChange(id, 'max', e.target.value)}
+                            error={Boolean(fieldErrors[id]?.max)}
+                            helperText={fieldErrors[id]?.max}
                             fullWidth
                             margin="normal"
                             InputLabelProps={{ shrink: true }}
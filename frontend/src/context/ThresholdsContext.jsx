@@ -3,6 +3,15 @@ import React, { createContext, useState, useEffect } from 'react';
 
 export const ThresholdsContext = createContext();
 
+// Заголовок If-Match для версии порога; без известной версии проверка не выполняется
+export const ifMatch = (version) => (version !== undefined ? `"${version}"` : '*');
+
+// Версия порога из ETag ответа на изменение
+export const etagVersion = (response) => {
+    const etag = response.headers.get('ETag');
+    return etag ? Number(etag.replace(/^W\//, '').replace(/"/g, '')) : undefined;
+};
+
 export function ThresholdsProvider({ children }) {
     const [thresholds, setThresholds] = useState(null);
     const [loading, setLoading] = useState(true);
@@ -24,7 +33,8 @@ export function ThresholdsProvider({ children }) {
             data.forEach(t => {
                 thresholdsMap[t.type] = { 
                     min: t.min_value, 
-                    max: t.max_value 
+                    max: t.max_value,
+                    version: t.version
                 };
             });
             
@@ -64,7 +74,9 @@ export function ThresholdsProvider({ children }) {
                 method: 'POST',
                 headers: { 
                     'Content-Type': 'application/json',
-                    'Accept': 'application/json'
+                    'Accept': 'application/json',
+                    // Версия, которую видел пользователь: сервер отклонит изменение, если порог уже изменили
+                    'If-Match': ifMatch(thresholds?.[type]?.version)
                 },
                 body: JSON.stringify({
                     type,
@@ -82,8 +94,21 @@ export function ThresholdsProvider({ children }) {
     
             const data = await response.json();
             
+            if (response.status === 409 && data.current) {
+                // Показываем актуальное значение, сохраненное другим пользователем
+                setThresholds(prev => ({
+                    ...prev,
+                    [type]: {
+                        min: data.current.min_value,
+                        max: data.current.max_value,
+                        version: data.current.version
+                    }
+                }));
+                throw new Error('Порог уже изменен другим пользователем, проверьте актуальные значения');
+            }
+
             if (!response.ok) {
-                throw new Error(data.error || 'Ошибка при обновлении порогов');
+                throw new Error(data.message || 'Ошибка при обновлении порогов');
             }
     
             // Обновление состояния
@@ -91,7 +116,8 @@ export function ThresholdsProvider({ children }) {
                 ...prev,
                 [type]: {
                     min: Number(newValues.min), 
-                    max: Number(newValues.max) 
+                    max: Number(newValues.max),
+                    version: etagVersion(response)
                 }
             }));
     